@@ -23,13 +23,39 @@ var DefaultBatchConfig = BatchConfig{
 }
 
 func (e *DB) Insert(table string, data interface{}, options ...string) (lastId int64, err error) {
-	cols, args, err := parseMap(ztype.ToMap(data), nil)
+	cols, args, err := e.parseWrite(table, data, writeInsert)
 	if err != nil {
 		return 0, err
 	}
 	return e.insertData(builder.Insert(table), cols, args, options...)
 }
 
+// InsertFrom inserts the rows selected by fn into the cols of table and returns the number of inserted rows,
+// fn must set the source with From, options such as ON CONFLICT DO NOTHING are appended to the INSERT
+func (e *DB) InsertFrom(
+	table string,
+	cols []string,
+	fn func(b *builder.SelectBuilder) error,
+	options ...string,
+) (int64, error) {
+	if fn == nil {
+		return 0, errors.New("insert the select cannot be empty")
+	}
+	if err := e.checkColumns(table, cols); err != nil {
+		return 0, err
+	}
+	sb := builder.Query("").SetDriver(e.driver).SetStrict(e.strict).SetEncoder(e.codecs.encode)
+	if err := fn(sb); err != nil {
+		return 0, err
+	}
+
+	b := builder.Insert(table).SetDriver(e.driver).SetStrict(e.strict).SetEncoder(e.codecs.encode).Cols(cols...).Select(sb)
+	if len(options) > 0 {
+		b.Option(options...)
+	}
+	return parseExec(e, b)
+}
+
 func (e *DB) BatchInsert(
 	table string,
 	data interface{},
@@ -45,7 +71,7 @@ func (e *DB) BatchInsertWithConfig(
 	config BatchConfig,
 	options ...string,
 ) (lastId []int64, err error) {
-	cols, args, err := parseMaps2(ztype.ToMaps(data))
+	cols, args, err := e.parseWrites(table, data, writeInsert)
 	if err != nil {
 		return []int64{0}, err
 	}
@@ -95,7 +121,7 @@ func (e *DB) insertDataReturningIDs(
 	args [][]interface{},
 	options ...string,
 ) ([]int64, error) {
-	b.SetDriver(e.driver)
+	b.SetDriver(e.driver).SetStrict(e.strict).SetEncoder(e.codecs.encode)
 
 	if len(options) > 0 {
 		b.Option(options...)
@@ -121,7 +147,7 @@ func (e *DB) insertDataReturningIDs(
 		if idKey == "" {
 			idKey = builder.IDKey
 		}
-		rows, err := e.QueryToMaps(sql+" RETURNING "+driverValue.Quote(idKey), values...)
+		rows, err := e.queryToMaps(sql+" RETURNING "+driverValue.Quote(idKey), values)
 		if err != nil {
 			return nil, err
 		}
@@ -135,7 +161,7 @@ func (e *DB) insertDataReturningIDs(
 		return ids, nil
 	}
 
-	result, err := e.Exec(sql, values...)
+	result, err := e.exec(sql, values)
 	if err != nil {
 		return nil, err
 	}
@@ -232,7 +258,7 @@ func (e *DB) batchWriteStrict(
 }
 
 func (e *DB) Replace(table string, data interface{}, options ...string) (lastId int64, err error) {
-	cols, args, err := parseMap(ztype.ToMap(data), nil)
+	cols, args, err := e.parseWrite(table, data, writeInsert)
 	if err != nil {
 		return 0, err
 	}
@@ -253,7 +279,7 @@ func (e *DB) BatchReplaceWithConfig(
 	config BatchConfig,
 	options ...string,
 ) (lastId []int64, err error) {
-	cols, args, err := parseMaps2(ztype.ToMaps(data))
+	cols, args, err := e.parseWrites(table, data, writeInsert)
 	if err != nil {
 		return []int64{0}, err
 	}
@@ -317,16 +343,18 @@ func (e *DB) Pages(
 		return resultMap, Pages{}, err
 	}
 
-	sql, values, err := b.Select(b.As("count(*)", "total")).Limit(-1).OrderBy().Offset(-1).Build()
+	count := b.Clone()
+	sql, values, err := count.Select(count.As(count.Cond.Var(builder.Raw("count(*)")), "total")).Limit(-1).OrderBy().Offset(-1).Build()
 	if err != nil {
 		return resultMap, Pages{}, err
 	}
 
-	rows, err := e.Query(sql, values...)
+	rows, err := e.query(sql, values)
 
 	if err == nil {
-		if m, _, err := ScanToMap(rows); err == nil {
-			pages.Total = uint(m[0]["total"].(int64))
+		defer rows.Close()
+		if m, _, err := e.scanToMap(rows); err == nil {
+			pages.Total = ztype.ToUint(m[0]["total"])
 			pages.Count = uint(math.Ceil(float64(pages.Total) / float64(pagesize)))
 		}
 	}
@@ -335,18 +363,21 @@ func (e *DB) Pages(
 }
 
 func (e *DB) Find(table string, fn func(b *builder.SelectBuilder) error) (ztype.Maps, error) {
-	b := builder.Query(table).SetDriver(e.driver)
+	b := builder.Query(table).SetDriver(e.driver).SetStrict(e.strict).SetEncoder(e.codecs.encode)
 	if fn != nil {
 		if err := fn(b); err != nil {
 			return []ztype.Map{}, err
 		}
 	}
+	if err := e.checkFind(table, b); err != nil {
+		return []ztype.Map{}, err
+	}
 
 	return parseQuery(e, b)
 }
 
 func (e *DB) Delete(table string, fn func(b *builder.DeleteBuilder) error) (int64, error) {
-	b := builder.Delete(table).SetDriver(e.driver)
+	b := builder.Delete(table).SetDriver(e.driver).SetStrict(e.strict).SetEncoder(e.codecs.encode)
 	if err := fn(b); err != nil {
 		return 0, err
 	}
@@ -361,7 +392,7 @@ func (e *DB) update(
 	fn func(b *builder.UpdateBuilder) error,
 	options ...string,
 ) (int64, error) {
-	b := builder.Update(table).SetDriver(e.driver)
+	b := builder.Update(table).SetDriver(e.driver).SetStrict(e.strict).SetEncoder(e.codecs.encode)
 	if fn == nil {
 		return 0, errors.New("update the condition cannot be empty")
 	}
@@ -393,7 +424,7 @@ func (e *DB) Update(
 	data interface{},
 	fn func(b *builder.UpdateBuilder) error,
 ) (int64, error) {
-	cols, args, err := parseMap(ztype.ToMap(data), nil)
+	cols, args, err := e.parseWrite(table, data, writeUpdate)
 	if err != nil {
 		return 0, err
 	}
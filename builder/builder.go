@@ -18,9 +18,22 @@ var _ Builder = new(compiledBuilder)
 
 func (cb *compiledBuilder) Build() (sql string, values []interface{}, err error) {
 	sql, values = cb.Cond.Compile(cb.format)
+	if cb.Cond.err != nil {
+		return "", nil, cb.Cond.err
+	}
 	return
 }
 
+func (cb *compiledBuilder) nestedBuild(parent *BuildCond, blend bool, initial []interface{}) (string, []interface{}, error) {
+	cond := cb.Cond.clone()
+	cond.inherit(parent)
+	if blend {
+		return cond.CompileString(cb.format), initial, cond.err
+	}
+	sql, values := cond.Compile(cb.format, initial...)
+	return sql, values, cond.err
+}
+
 func (cb *compiledBuilder) BuildWithFlavor(flavor driver.Typ, initialArg ...interface{}) (sql string, args []interface{}) {
 	return cb.Cond.Compile(cb.format, initialArg...)
 }
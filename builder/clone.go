@@ -0,0 +1,167 @@
+package builder
+
+// Clone returns a deep copy of the SELECT, changes to the copy do not affect b
+func (b *SelectBuilder) Clone() *SelectBuilder {
+	c := *b
+	c.Cond = b.Cond.clone()
+	c.havingExprs = cloneStrings(b.havingExprs)
+	c.joinOptions = append([]JoinOption(nil), b.joinOptions...)
+	c.joinTables = cloneStrings(b.joinTables)
+	c.joinExprs = make([][]string, len(b.joinExprs))
+	for i := range b.joinExprs {
+		c.joinExprs[i] = cloneStrings(b.joinExprs[i])
+	}
+	c.joinUsing = make([][]string, len(b.joinUsing))
+	for i := range b.joinUsing {
+		c.joinUsing[i] = cloneStrings(b.joinUsing[i])
+	}
+	c.joinLateral = append([]bool(nil), b.joinLateral...)
+	c.windows = make([]namedWindow, len(b.windows))
+	for i := range b.windows {
+		c.windows[i] = namedWindow{name: b.windows[i].name, spec: b.windows[i].spec.clone()}
+	}
+	c.whereExprs = cloneStrings(b.whereExprs)
+	c.groupByCols = cloneStrings(b.groupByCols)
+	c.orderByCols = cloneStrings(b.orderByCols)
+	c.selectCols = cloneStrings(b.selectCols)
+	c.tables = cloneStrings(b.tables)
+	c.lock.tables = cloneStrings(b.lock.tables)
+	c.with = b.with.clone()
+	return &c
+}
+
+// Clone returns a deep copy of the UPDATE, changes to the copy do not affect b
+func (b *UpdateBuilder) Clone() *UpdateBuilder {
+	c := *b
+	c.Cond = b.Cond.clone()
+	c.assignments = cloneStrings(b.assignments)
+	c.whereExprs = cloneStrings(b.whereExprs)
+	c.orderByCols = cloneStrings(b.orderByCols)
+	c.options = make([][]string, len(b.options))
+	for i := range b.options {
+		c.options[i] = cloneStrings(b.options[i])
+	}
+	c.sources = b.sources.clone()
+	c.with = b.with.clone()
+	return &c
+}
+
+// Clone returns a deep copy of the DELETE, changes to the copy do not affect b
+func (b *DeleteBuilder) Clone() *DeleteBuilder {
+	c := *b
+	c.Cond = b.Cond.clone()
+	c.whereExprs = cloneStrings(b.whereExprs)
+	c.orderByCols = cloneStrings(b.orderByCols)
+	c.sources = b.sources.clone()
+	c.with = b.with.clone()
+	return &c
+}
+
+// Clone returns a deep copy of the INSERT and of its SELECT
+func (b *InsertBuilder) Clone() *InsertBuilder {
+	c := *b
+	c.cond = b.cond.clone()
+	c.cols = cloneStrings(b.cols)
+	c.values = make([][]string, len(b.values))
+	for i := range b.values {
+		c.values[i] = cloneStrings(b.values[i])
+	}
+	c.options = make([][]string, len(b.options))
+	for i := range b.options {
+		c.options[i] = cloneStrings(b.options[i])
+	}
+	if b.query != nil {
+		for i := range b.cond.values {
+			if q, ok := b.cond.values[i].(*SelectBuilder); ok && q == b.query {
+				c.query = c.cond.values[i].(*SelectBuilder)
+			}
+		}
+	}
+	c.with = b.with.clone()
+	return &c
+}
+
+// Clone returns a deep copy of the UNION and of the queries it combines
+func (b *UnionBuilder) Clone() *UnionBuilder {
+	c := *b
+	c.cond = b.cond.clone()
+	c.builders = make([]*SelectBuilder, len(b.builders))
+	for i := range b.builders {
+		c.builders[i] = b.builders[i].Clone()
+	}
+	c.sets = make([]setOperand, len(b.sets))
+	for i := range b.sets {
+		c.sets[i] = b.sets[i]
+		if builder, ok := cloneArg(b.sets[i].builder).(Builder); ok {
+			c.sets[i].builder = builder
+		}
+	}
+	c.orderByCols = cloneStrings(b.orderByCols)
+	return &c
+}
+
+// clone returns a copy of c that adds the arguments of c again in the same order,
+// so named and lazy arguments are kept, the builders among them are cloned so
+// that the copy never shares a subquery with c
+func (c *BuildCond) clone() *BuildCond {
+	n := newCond(c.driver, c.onlyNamed)
+	n.strict, n.err, n.encoder = c.strict, c.err, c.encoder
+	for i := range c.values {
+		n.Var(cloneArg(c.values[i]))
+	}
+	return n
+}
+
+func cloneArg(arg interface{}) interface{} {
+	switch v := arg.(type) {
+	case *SelectBuilder:
+		return v.Clone()
+	case *UpdateBuilder:
+		return v.Clone()
+	case *DeleteBuilder:
+		return v.Clone()
+	case *UnionBuilder:
+		return v.Clone()
+	case *InsertBuilder:
+		return v.Clone()
+	case *compiledBuilder:
+		return &compiledBuilder{Cond: v.Cond.clone(), format: v.format}
+	}
+	return arg
+}
+
+func (w withClause) clone() withClause {
+	ctes := make([]cte, len(w.ctes))
+	for i := range w.ctes {
+		ctes[i] = cte{name: w.ctes[i].name, cols: cloneStrings(w.ctes[i].cols)}
+		if b, ok := cloneArg(w.ctes[i].builder).(Builder); ok {
+			ctes[i].builder = b
+		}
+	}
+	return withClause{ctes: ctes, recursive: w.recursive}
+}
+
+func (s sources) clone() sources {
+	joins := make([]sourceJoin, len(s.joins))
+	for i := range s.joins {
+		joins[i] = sourceJoin{option: s.joins[i].option, table: s.joins[i].table, on: cloneStrings(s.joins[i].on)}
+	}
+	return sources{joins: joins, tables: cloneStrings(s.tables)}
+}
+
+func (w *WindowSpec) clone() *WindowSpec {
+	if w == nil {
+		return nil
+	}
+	c := *w
+	c.partition = cloneStrings(w.partition)
+	c.orders = cloneStrings(w.orders)
+	return &c
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(s)), s...)
+}
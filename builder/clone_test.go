@@ -0,0 +1,171 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver/postgres"
+)
+
+func TestSelectClone(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sub := builder.Query("order").Select("user_id")
+	sub.Where(sub.Cond.GT("amount", 100))
+
+	base := builder.Query("user").Select("id", "name").SetDriver(&postgres.Config{})
+	base.Where(base.Cond.EQ("status", 1), base.Cond.In("id", sub))
+	base.Join("profile p", "p.user_id = user.id").OrderBy("id").Limit(10)
+
+	c := base.Clone()
+	c.Where(c.Cond.LT("age", 30)).OrderBy().Limit(-1).Select("count(*)")
+	sub.Where(sub.Cond.EQ("paid", true))
+
+	sql, values, err := base.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT "id", "name" FROM "user" JOIN profile p ON p.user_id = user.id WHERE "status" = $1 AND "id" IN (SELECT "user_id" FROM "order" WHERE "amount" > $2 AND "paid" = $3) ORDER BY id LIMIT 10`, sql)
+	tt.Equal([]interface{}{1, 100, true}, values)
+
+	sql, values, err = c.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT count(*) FROM "user" JOIN profile p ON p.user_id = user.id WHERE "status" = $1 AND "id" IN (SELECT "user_id" FROM "order" WHERE "amount" > $2) AND "age" < $3`, sql)
+	tt.Equal([]interface{}{1, 100, 30}, values)
+
+	w := builder.Query("t").SetDriver(&postgres.Config{})
+	w.Window("w", builder.Window().PartitionBy("a")).ForUpdate().Of("t")
+	wc := w.Clone()
+	wc.Of("x")
+	tt.Equal(`SELECT * FROM "t" WINDOW "w" AS (PARTITION BY "a") FOR UPDATE OF "t"`, w.String())
+}
+
+func TestWriteClone(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	ub := builder.Update("user")
+	ub.Set(ub.Assign("name", "a")).Where(ub.Cond.EQ("id", 1))
+	uc := ub.Clone()
+	uc.SetMore(uc.Assign("age", 2)).Where(uc.Cond.EQ("status", 0))
+
+	sql, values, err := ub.Build()
+	tt.NoError(err)
+	tt.Equal(`UPDATE "user" SET "name" = ? WHERE "id" = ?`, sql)
+	tt.Equal([]interface{}{"a", 1}, values)
+
+	sql, values, err = uc.Build()
+	tt.NoError(err)
+	tt.Equal(`UPDATE "user" SET "name" = ?, "age" = ? WHERE "id" = ? AND "status" = ?`, sql)
+	tt.Equal([]interface{}{"a", 2, 1, 0}, values)
+
+	db := builder.Delete("user")
+	db.Where(db.Cond.EQ("id", 1))
+	dc := db.Clone()
+	dc.Where(dc.Cond.EQ("status", 0))
+
+	sql, values, err = db.Build()
+	tt.NoError(err)
+	tt.Equal(`DELETE FROM "user" WHERE "id" = ?`, sql)
+	tt.Equal([]interface{}{1}, values)
+
+	sql, values, err = dc.Build()
+	tt.NoError(err)
+	tt.Equal(`DELETE FROM "user" WHERE "id" = ? AND "status" = ?`, sql)
+	tt.Equal([]interface{}{1, 0}, values)
+
+	ib := builder.Insert("user").Cols("name").Values("a")
+	ic := ib.Clone().Values("b")
+
+	sql, values, err = ib.Build()
+	tt.NoError(err)
+	tt.Equal(`INSERT INTO "user" ("name") VALUES (?)`, sql)
+	tt.Equal([]interface{}{"a"}, values)
+
+	sql, values, err = ic.Build()
+	tt.NoError(err)
+	tt.Equal(`INSERT INTO "user" ("name") VALUES (?), (?)`, sql)
+	tt.Equal([]interface{}{"a", "b"}, values)
+
+	query := builder.Query("tmp").Select("name")
+	query.Where(query.Cond.EQ("ok", 1))
+	is := builder.Insert("user").Cols("name").Select(query)
+	isc := is.Clone()
+	query.Where(query.Cond.EQ("x", 2))
+
+	sql, values, err = isc.Build()
+	tt.NoError(err)
+	tt.Equal(`INSERT INTO "user" ("name") SELECT "name" FROM "tmp" WHERE "ok" = ?`, sql)
+	tt.Equal([]interface{}{1}, values)
+}
+
+func TestUnionClone(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	a := builder.Query("a").Select("id")
+	a.Where(a.Cond.EQ("x", 1))
+	u := builder.UnionAll(a, builder.Query("b").Select("id"))
+	uc := u.Clone().Limit(5)
+	a.Where(a.Cond.EQ("y", 2))
+
+	sql, values, err := uc.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT "id" FROM "a" WHERE "x" = ? UNION ALL SELECT "id" FROM "b" LIMIT 5`, sql)
+	tt.Equal([]interface{}{1}, values)
+
+	sql, values, err = u.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT "id" FROM "a" WHERE "x" = ? AND "y" = ? UNION ALL SELECT "id" FROM "b"`, sql)
+	tt.Equal([]interface{}{1, 2}, values)
+}
+
+func TestCloneNamed(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	calls := 0
+	sb := builder.Query("t").SetDriver(&postgres.Config{})
+	sb.Cond.Var(builder.Named("x", 5))
+	sb.Cond.Var(builder.Named("now", func() interface{} {
+		calls++
+		return calls
+	}))
+	sb.Where("a = ${x}", sb.Cond.EQ("b", 2), "c < ${now}")
+
+	c := sb.Clone()
+	tt.Equal(0, calls)
+
+	sql, values, err := c.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "t" WHERE a = $1 AND "b" = $2 AND c < $3`, sql)
+	tt.Equal([]interface{}{5, 2, 1}, values)
+
+	sql, values, err = sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "t" WHERE a = $1 AND "b" = $2 AND c < $3`, sql)
+	tt.Equal([]interface{}{5, 2, 2}, values)
+}
+
+func TestScope(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	builder.RegisterScope("active", func(b *builder.SelectBuilder) {
+		b.Where(b.Cond.EQ("status", 1), b.Cond.IsNull("deleted_at"))
+	})
+	recent := func(b *builder.SelectBuilder) {
+		b.OrderBy("created_at DESC").Limit(10)
+	}
+
+	sb := builder.Query("user").UseScope("active").Apply(recent)
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" WHERE "status" = ? AND "deleted_at" IS NULL ORDER BY created_at DESC LIMIT 10`, sql)
+	tt.Equal([]interface{}{1}, values)
+
+	active, ok := builder.LookupScope("active")
+	tt.EqualTrue(ok)
+	sb = builder.Query("post").Apply(builder.Scopes(active, recent))
+	sql, _, err = sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "post" WHERE "status" = ? AND "deleted_at" IS NULL ORDER BY created_at DESC LIMIT 10`, sql)
+
+	_, _, err = builder.Query("user").UseScope("missing").Build()
+	tt.EqualTrue(err != nil)
+}
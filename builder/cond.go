@@ -10,12 +10,19 @@ import (
 
 type BuildCond struct {
 	driver driver.Dialect
+	err    error
 	zutil.Args
+	// values are the arguments passed to Var in order, clone adds them again
+	values    []interface{}
+	encoder   Encoder
+	strict    bool
+	onlyNamed bool
 }
 
 func newCond(d driver.Dialect, onlyNamed bool) *BuildCond {
 	args := &BuildCond{
-		driver: d,
+		driver:    d,
+		onlyNamed: onlyNamed,
 	}
 
 	opts := []zutil.ArgsOpt{argsCompileHandler(args)}
@@ -28,10 +35,20 @@ func newCond(d driver.Dialect, onlyNamed bool) *BuildCond {
 	return args
 }
 
+// CompileString compiles format with the text of the arguments in place of
+// the placeholders, it is meant for debugging, see Inline for SQL that can be run
+func (c *BuildCond) CompileString(format string, initialValue ...interface{}) string {
+	args := c.Args
+	zutil.WithCompileHandler(c.compileHandler(true))(&args)
+	sql, _ := args.Compile(format, initialValue...)
+	return sql
+}
+
 func (c *BuildCond) Var(value interface{}) string {
 	if arg, ok := value.(sql.NamedArg); ok && c.driver.Value() != driver.MsSQL {
 		value = arg.Value
 	}
+	c.values = append(c.values, value)
 	return c.Args.Var(value)
 }
 
@@ -93,6 +110,60 @@ func (c *BuildCond) NotIn(field string, value ...interface{}) string {
 	return c.quoteField(field) + " NOT IN (" + strings.Join(vs, ", ") + ")"
 }
 
+// InQuery represents "Field IN (subquery)"
+func (c *BuildCond) InQuery(field string, sb *SelectBuilder) string {
+	return c.quoteField(field) + " IN (" + c.Var(sb) + ")"
+}
+
+// NotInQuery represents "Field NOT IN (subquery)"
+func (c *BuildCond) NotInQuery(field string, sb *SelectBuilder) string {
+	return c.quoteField(field) + " NOT IN (" + c.Var(sb) + ")"
+}
+
+// Exists represents "EXISTS (subquery)"
+func (c *BuildCond) Exists(sb *SelectBuilder) string {
+	return "EXISTS (" + c.Var(sb) + ")"
+}
+
+// NotExists represents "NOT EXISTS (subquery)"
+func (c *BuildCond) NotExists(sb *SelectBuilder) string {
+	return "NOT EXISTS (" + c.Var(sb) + ")"
+}
+
+// EQQuery represents "Field = (subquery)", the subquery must return a single value
+func (c *BuildCond) EQQuery(field string, sb *SelectBuilder) string {
+	return c.queryCond(field, " = ", sb)
+}
+
+// NEQuery represents "Field <> (subquery)"
+func (c *BuildCond) NEQuery(field string, sb *SelectBuilder) string {
+	return c.queryCond(field, " <> ", sb)
+}
+
+// GTQuery represents "Field > (subquery)"
+func (c *BuildCond) GTQuery(field string, sb *SelectBuilder) string {
+	return c.queryCond(field, " > ", sb)
+}
+
+// GEQuery represents "Field >= (subquery)"
+func (c *BuildCond) GEQuery(field string, sb *SelectBuilder) string {
+	return c.queryCond(field, " >= ", sb)
+}
+
+// LTQuery represents "Field < (subquery)"
+func (c *BuildCond) LTQuery(field string, sb *SelectBuilder) string {
+	return c.queryCond(field, " < ", sb)
+}
+
+// LEQuery represents "Field <= (subquery)"
+func (c *BuildCond) LEQuery(field string, sb *SelectBuilder) string {
+	return c.queryCond(field, " <= ", sb)
+}
+
+func (c *BuildCond) queryCond(field, condition string, sb *SelectBuilder) string {
+	return c.quoteField(field) + condition + "(" + c.Var(sb) + ")"
+}
+
 // Like represents "Field LIKE value"
 func (c *BuildCond) Like(field string, value interface{}) string {
 	return c.quoteField(field) + " LIKE " + c.Var(value)
@@ -185,5 +256,5 @@ func (c *BuildCond) Cond(field, condition string, value interface{}) string {
 }
 
 func (c *BuildCond) quoteField(field string) string {
-	return c.driver.Value().Quote(Escape(field))
+	return c.quote(Escape(field))
 }
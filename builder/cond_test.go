@@ -5,6 +5,7 @@ import (
 
 	"github.com/sohaha/zlsgo"
 	"github.com/sohaha/zlsgo/zsync"
+	"github.com/zlsgo/zdb/driver/mssql"
 	"github.com/zlsgo/zdb/driver/mysql"
 	"github.com/zlsgo/zdb/driver/postgres"
 )
@@ -41,3 +42,62 @@ func TestCond(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestSubqueryCond(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	orders := Query("orders o").Select("1")
+	orders.Where(orders.Cond.EQ("o.user_id", Column("u.id")), orders.Cond.GT("o.amount", 100))
+
+	maxAge := Select("MAX(age)").From("user")
+	maxAge.Where(maxAge.Cond.EQ("status", 1))
+
+	vip := Select("user_id").From("vip")
+	vip.Where(vip.Cond.EQ("level", 3))
+
+	sb := Query("user u").SetDriver(&postgres.Config{})
+	sb.Where(
+		sb.Cond.EQ("u.status", 1),
+		sb.Cond.Exists(orders),
+		sb.Cond.NotExists(Query("ban").Select("1").Where(`"ban"."uid" = "u"."id"`)),
+		sb.Cond.InQuery("u.id", vip),
+		sb.Cond.NotInQuery("u.id", Query("black").Select("uid")),
+		sb.Cond.LTQuery("u.age", maxAge),
+	)
+
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" u WHERE "u"."status" = $1 AND `+
+		`EXISTS (SELECT 1 FROM "orders" o WHERE "o"."user_id" = "u"."id" AND "o"."amount" > $2) AND `+
+		`NOT EXISTS (SELECT 1 FROM "ban" WHERE "ban"."uid" = "u"."id") AND `+
+		`"u"."id" IN (SELECT "user_id" FROM "vip" WHERE "level" = $3) AND `+
+		`"u"."id" NOT IN (SELECT "uid" FROM "black") AND `+
+		`"u"."age" < (SELECT MAX(age) FROM "user" WHERE "status" = $4)`, sql)
+	tt.Equal([]interface{}{1, 100, 3, 1}, values)
+
+	ms := Query("user u").SetDriver(&mssql.Config{})
+	ms.Where(ms.Cond.EQ("u.status", 1), ms.Cond.GEQuery("u.score", maxAge), ms.Cond.Exists(orders))
+	sql, values, err = ms.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" u WHERE "u"."status" = @p1 AND "u"."score" >= (SELECT MAX(age) FROM "user" WHERE "status" = @p2) AND `+
+		`EXISTS (SELECT 1 FROM "orders" o WHERE "o"."user_id" = "u"."id" AND "o"."amount" > @p3)`, sql)
+	tt.Equal([]interface{}{1, 1, 100}, values)
+}
+
+func TestSelectLiteral(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sb := Query("user").Select("1", "2a", "id")
+	tt.Equal(`SELECT 1, "2a", "id" FROM "user"`, sb.String())
+
+	sb = Query("user").SetStrict(true).Select("1")
+	sql, _, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT "1" FROM "user"`, sql)
+
+	ub := Update("user")
+	ub.Set(ub.Assign("1", 2)).Where(ub.Cond.EQ("id", 1))
+	sql, _, err = ub.Build()
+	tt.NoError(err)
+	tt.Equal(`UPDATE "user" SET "1" = ? WHERE "id" = ?`, sql)
+}
@@ -1,8 +1,10 @@
 package builder
 
 import (
+	"bytes"
 	"errors"
 	"strconv"
+	"strings"
 
 	"github.com/sohaha/zlsgo/zutil"
 	"github.com/zlsgo/zdb/driver"
@@ -11,10 +13,13 @@ import (
 // DeleteBuilder is a builder to build DELETE
 type DeleteBuilder struct {
 	Cond        *BuildCond
+	err         error
 	table       string
 	order       string
 	whereExprs  []string
 	orderByCols []string
+	sources     sources
+	with        withClause
 	limit       int
 	limitBy     string
 }
@@ -37,12 +42,53 @@ func (b *DeleteBuilder) SetDriver(driver driver.Dialect) *DeleteBuilder {
 	return b
 }
 
+// With adds a common table expression before the DELETE, cols optionally names its columns
+func (b *DeleteBuilder) With(name string, builder Builder, cols ...string) *DeleteBuilder {
+	b.with.add(false, name, builder, cols)
+	return b
+}
+
+// WithRecursive adds a recursive common table expression before the DELETE
+func (b *DeleteBuilder) WithRecursive(name string, builder Builder, cols ...string) *DeleteBuilder {
+	b.with.add(true, name, builder, cols)
+	return b
+}
+
+// Join adds an inner JOIN to the tables the DELETE reads, MySQL and MSSQL write DELETE target FROM target JOIN,
+// PostgreSQL moves it to USING with the ON expressions in WHERE and SQLite to an EXISTS subquery
+func (b *DeleteBuilder) Join(table string, onExpr ...string) *DeleteBuilder {
+	return b.JoinWithOption("", table, onExpr...)
+}
+
+// JoinWithOption adds a JOIN with option, only inner joins are supported by PostgreSQL and SQLite
+func (b *DeleteBuilder) JoinWithOption(option JoinOption, table string, onExpr ...string) *DeleteBuilder {
+	b.sources.join(option, table, onExpr)
+	return b
+}
+
+// Using adds tables the DELETE reads, they are joined by the conditions in WHERE
+func (b *DeleteBuilder) Using(table ...string) *DeleteBuilder {
+	b.sources.tables = append(b.sources.tables, table...)
+	return b
+}
+
 // Where sets expressions of WHERE in DELETE
 func (b *DeleteBuilder) Where(andExpr ...string) *DeleteBuilder {
 	b.whereExprs = append(b.whereExprs, andExpr...)
 	return b
 }
 
+// WhereBy adds the conditions of a map or a filter struct to WHERE, see BuildCond.Parse
+func (b *DeleteBuilder) WhereBy(cond interface{}) *DeleteBuilder {
+	expr, err := b.Cond.Parse(cond)
+	if err != nil {
+		b.err = err
+	} else if expr != "" {
+		b.whereExprs = append(b.whereExprs, expr)
+	}
+	return b
+}
+
 // OrderBy sets columns of ORDER BY in DELETE
 func (b *DeleteBuilder) OrderBy(col ...string) *DeleteBuilder {
 	b.orderByCols = col
@@ -80,18 +126,32 @@ func (b *DeleteBuilder) String() string {
 
 // Build returns compiled DELETE string and Cond
 func (b *DeleteBuilder) Build() (sql string, values []interface{}, err error) {
-	if len(b.whereExprs) == 0 {
-		return "", nil, errors.New("delete safety error: no where condition")
-	}
-	if b.limit >= 0 && b.Cond.driver.Value() != driver.MySQL && b.limitBy == "" {
-		return "", nil, errors.New("delete safety error: limit requires LimitBy for non-MySQL")
+	if err = b.check(); err != nil {
+		return "", nil, err
 	}
 
 	sql, values = b.build(false)
+	if b.Cond.err != nil {
+		return "", nil, b.Cond.err
+	}
 	return
 }
 
-func (b *DeleteBuilder) build(blend bool) (sql string, args []interface{}) {
+// check returns the errors that keep the DELETE from being run
+func (b *DeleteBuilder) check() error {
+	if b.err != nil {
+		return b.err
+	}
+	if len(b.whereExprs) == 0 {
+		return errors.New("delete safety error: no where condition")
+	}
+	if b.limit >= 0 && b.Cond.driver.Value() != driver.MySQL && b.limitBy == "" {
+		return errors.New("delete safety error: limit requires LimitBy for non-MySQL")
+	}
+	return b.sources.check("delete", b.Cond.driver.Value(), b.limit)
+}
+
+func (b *DeleteBuilder) build(blend bool, initial ...interface{}) (sql string, args []interface{}) {
 	// More accurate size estimation
 	estimatedSize := 12 + len(b.table) + 4 // "DELETE FROM " + table + quotes
 	
@@ -116,47 +176,94 @@ func (b *DeleteBuilder) build(blend bool) (sql string, args []interface{}) {
 		estimatedSize += 15 // " LIMIT " + number or complex subquery
 	}
 
+	prefix, initial := b.with.build(b.Cond, blend, initial)
+
 	buf := zutil.GetBuff(uint(estimatedSize))
 	defer zutil.PutBuff(buf)
 
 	driverValue := b.Cond.driver.Value()
 
-	buf.WriteString("DELETE FROM ")
-	buf.WriteString(driverValue.Quote(b.table))
+	if !b.sources.empty() {
+		b.buildJoined(buf, driverValue)
+	} else {
+		buf.WriteString("DELETE FROM ")
+		buf.WriteString(b.Cond.quote(b.table))
 
-	if b.limit >= 0 {
-		if driverValue != driver.MySQL {
-			limitByQuoted := driverValue.Quote(b.limitBy)
-			buf.WriteString(" WHERE ")
-			buf.WriteString(limitByQuoted)
-			buf.WriteString(" IN (")
-
-			buf.WriteString("SELECT ")
-			buf.WriteString(limitByQuoted)
-			buf.WriteString(" FROM ")
-			buf.WriteString(driverValue.Quote(b.table))
-			buf.Write(b.buildStatement())
-			buf.WriteString(" LIMIT ")
-			buf.WriteString(strconv.Itoa(b.limit))
+		if b.limit >= 0 {
+			if driverValue != driver.MySQL {
+				limitByQuoted := b.Cond.quote(b.limitBy)
+				buf.WriteString(" WHERE ")
+				buf.WriteString(limitByQuoted)
+				buf.WriteString(" IN (")
 
-			buf.WriteString(")")
+				buf.WriteString("SELECT ")
+				buf.WriteString(limitByQuoted)
+				buf.WriteString(" FROM ")
+				buf.WriteString(b.Cond.quote(b.table))
+				buf.Write(b.buildStatement())
+				buf.WriteString(" LIMIT ")
+				buf.WriteString(strconv.Itoa(b.limit))
+
+				buf.WriteString(")")
+			} else {
+				buf.Write(b.buildStatement())
+
+				buf.WriteString(" LIMIT ")
+				buf.WriteString(strconv.Itoa(b.limit))
+			}
 		} else {
 			buf.Write(b.buildStatement())
-
-			buf.WriteString(" LIMIT ")
-			buf.WriteString(strconv.Itoa(b.limit))
 		}
-	} else {
-		buf.Write(b.buildStatement())
 	}
 
 	if blend {
-		return b.Cond.CompileString(buf.String()), nil
+		return prefix + b.Cond.CompileString(buf.String(), initial...), nil
 	}
 
-	return b.Cond.Compile(buf.String())
+	sql, args = b.Cond.Compile(buf.String(), initial...)
+	return prefix + sql, args
 }
 
 func (b *DeleteBuilder) buildStatement() []byte {
 	return buildWhereOrderStatement(b.Cond, b.whereExprs, b.orderByCols, b.order)
 }
+
+// buildJoined writes the DELETE reading joined tables in the syntax of the dialect
+func (b *DeleteBuilder) buildJoined(buf *bytes.Buffer, d driver.Typ) {
+	whereExprs := b.whereExprs
+	switch d {
+	case driver.PostgreSQL, driver.Doris:
+		buf.WriteString("DELETE FROM ")
+		buf.WriteString(aliasTable(b.Cond, b.table))
+		tables, conds := b.sources.flatten(b.Cond)
+		buf.WriteString(" USING ")
+		buf.WriteString(strings.Join(tables, ", "))
+		whereExprs = append(conds, whereExprs...)
+	case driver.SQLite:
+		buf.WriteString("DELETE FROM ")
+		buf.WriteString(aliasTable(b.Cond, b.table))
+		tables, conds := b.sources.flatten(b.Cond)
+		exists := "EXISTS (SELECT 1 FROM " + strings.Join(tables, ", ")
+		if conds = append(conds, whereExprs...); len(conds) > 0 {
+			exists += " WHERE " + strings.Join(conds, " AND ")
+		}
+		whereExprs = []string{exists + ")"}
+	default:
+		buf.WriteString("DELETE ")
+		buf.WriteString(targetName(b.Cond, b.table))
+		buf.WriteString(" FROM ")
+		buf.WriteString(b.Cond.quote(b.table))
+		b.sources.write(buf, b.Cond)
+	}
+	buf.Write(buildWhereOrderStatement(b.Cond, whereExprs, b.orderByCols, b.order))
+}
+
+func (b *DeleteBuilder) nestedBuild(parent *BuildCond, blend bool, initial []interface{}) (string, []interface{}, error) {
+	c := b.Clone()
+	c.Cond.inherit(parent)
+	if err := c.check(); err != nil {
+		return "", initial, err
+	}
+	sql, values := c.build(blend, initial...)
+	return sql, values, c.Cond.err
+}
@@ -6,7 +6,10 @@ import (
 
 	"github.com/sohaha/zlsgo"
 	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver"
+	"github.com/zlsgo/zdb/driver/mssql"
 	"github.com/zlsgo/zdb/driver/mysql"
+	"github.com/zlsgo/zdb/driver/postgres"
 	"github.com/zlsgo/zdb/driver/sqlite3"
 )
 
@@ -97,3 +100,47 @@ func TestDeleteNoWhere(t *testing.T) {
 	_, _, err := d.Build()
 	tt.EqualTrue(err != nil)
 }
+
+func TestDeleteJoin(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	build := func(d driver.Dialect) (string, []interface{}, error) {
+		db := builder.Delete("user u").SetDriver(d)
+		db.Join("ban b", "b.user_id = u.id").Where(db.Cond.LT("b.expired_at", 100))
+		return db.Build()
+	}
+
+	sql, values, err := build(&mysql.Config{})
+	tt.NoError(err)
+	tt.Equal("DELETE u FROM `user` u JOIN ban b ON b.user_id = u.id WHERE `b`.`expired_at` < ?", sql)
+	tt.Equal([]interface{}{100}, values)
+
+	sql, _, err = build(&mssql.Config{})
+	tt.NoError(err)
+	tt.Equal(`DELETE u FROM "user" u JOIN ban b ON b.user_id = u.id WHERE "b"."expired_at" < @p1`, sql)
+
+	sql, _, err = build(&postgres.Config{})
+	tt.NoError(err)
+	tt.Equal(`DELETE FROM "user" AS u USING ban b WHERE b.user_id = u.id AND "b"."expired_at" < $1`, sql)
+
+	sql, _, err = build(&sqlite3.Config{})
+	tt.NoError(err)
+	tt.Equal(`DELETE FROM "user" AS u WHERE EXISTS (SELECT 1 FROM ban b WHERE b.user_id = u.id AND "b"."expired_at" < ?)`, sql)
+
+	db := builder.Delete("user").SetDriver(&postgres.Config{}).Using("ban")
+	db.Where("ban.user_id = \"user\".id")
+	sql, _, err = db.Build()
+	tt.NoError(err)
+	tt.Equal(`DELETE FROM "user" USING "ban" WHERE ban.user_id = "user".id`, sql)
+
+	db = builder.Delete("user").SetDriver(&mysql.Config{}).Using("ban")
+	db.Where("ban.user_id = user.id")
+	sql, _, err = db.Build()
+	tt.NoError(err)
+	tt.Equal("DELETE `user` FROM `user`, `ban` WHERE ban.user_id = user.id", sql)
+
+	db = builder.Delete("user").SetDriver(&sqlite3.Config{})
+	db.JoinWithOption(builder.LeftJoin, "ban", "ban.user_id = user.id").Where("ban.id IS NULL")
+	_, _, err = db.Build()
+	tt.EqualTrue(err != nil)
+}
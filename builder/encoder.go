@@ -0,0 +1,67 @@
+package builder
+
+// Encoder converts a value bound to a statement into a value accepted by the driver
+type Encoder func(v interface{}) (interface{}, error)
+
+// DefaultEncoder is applied to the values of the builders without an Encoder,
+// zdb sets it to the codecs registered with zdb.RegisterCodec
+var DefaultEncoder Encoder
+
+// SetEncoder sets the Encoder of the values of the SELECT, see BuildCond.SetEncoder
+func (b *SelectBuilder) SetEncoder(fn Encoder) *SelectBuilder {
+	b.Cond.SetEncoder(fn)
+	return b
+}
+
+// SetEncoder sets the Encoder of the values of the INSERT, see BuildCond.SetEncoder
+func (b *InsertBuilder) SetEncoder(fn Encoder) *InsertBuilder {
+	b.cond.SetEncoder(fn)
+	return b
+}
+
+// SetEncoder sets the Encoder of the values of the UPDATE, see BuildCond.SetEncoder
+func (b *UpdateBuilder) SetEncoder(fn Encoder) *UpdateBuilder {
+	b.Cond.SetEncoder(fn)
+	return b
+}
+
+// SetEncoder sets the Encoder of the values of the DELETE, see BuildCond.SetEncoder
+func (b *DeleteBuilder) SetEncoder(fn Encoder) *DeleteBuilder {
+	b.Cond.SetEncoder(fn)
+	return b
+}
+
+// SetEncoder converts the values when the statement is compiled, so that Build, String,
+// Render and Inline all show the encoded values. Subqueries without an Encoder use fn,
+// DefaultEncoder is used when fn is nil
+func (c *BuildCond) SetEncoder(fn Encoder) {
+	c.encoder = fn
+}
+
+// encode converts v with the Encoder of c, the error is returned by Build
+func (c *BuildCond) encode(v interface{}) interface{} {
+	fn := c.encoder
+	if fn == nil {
+		fn = DefaultEncoder
+	}
+	if fn == nil {
+		return v
+	}
+	val, err := fn(v)
+	if err != nil {
+		if c.err == nil {
+			c.err = err
+		}
+		return v
+	}
+	return val
+}
+
+// inherit compiles c as a part of parent, with the dialect of parent and
+// its Encoder when c has none
+func (c *BuildCond) inherit(parent *BuildCond) {
+	c.driver = parent.driver
+	if c.encoder == nil {
+		c.encoder = parent.encoder
+	}
+}
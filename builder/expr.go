@@ -0,0 +1,345 @@
+package builder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sohaha/zlsgo/zutil"
+	"github.com/zlsgo/zdb/driver"
+)
+
+type (
+	exprKind uint8
+	// Expr is an SQL expression such as CASE, COALESCE or CAST, it is written when the statement
+	// is compiled so that it follows the dialect and binds its values as arguments.
+	// Use it through Var of the Cond of the statement, e.g. in Select, Where, OrderBy or as the value of Assign.
+	// Its operands are bound as values, use Column for a column, Raw for SQL and Expr or a Builder to nest
+	Expr struct {
+		value   interface{}
+		orElse  interface{}
+		err     error
+		layout  string
+		whens   []exprWhen
+		args    []interface{}
+		kind    exprKind
+		simple  bool
+		hasElse bool
+	}
+	exprWhen struct {
+		when interface{}
+		then interface{}
+	}
+	// exprWriter writes an Expr into the statement being compiled
+	exprWriter struct {
+		buf     *bytes.Buffer
+		handle  zutil.ArgsCompileHandler
+		compile func(format string, values []interface{}) (string, []interface{})
+		values  []interface{}
+		d       driver.Typ
+	}
+)
+
+const (
+	exprCase exprKind = iota
+	exprCoalesce
+	exprNullIf
+	exprCast
+	exprConcat
+	exprDateTrunc
+	exprDateFormat
+)
+
+// castTypes are the portable types of Cast and their name in each dialect
+var castTypes = map[string]map[driver.Typ]string{
+	"string": {
+		driver.MySQL: "CHAR", driver.Doris: "VARCHAR", driver.PostgreSQL: "TEXT",
+		driver.SQLite: "TEXT", driver.MsSQL: "NVARCHAR(MAX)", driver.ClickHouse: "String",
+	},
+	"int": {
+		driver.MySQL: "SIGNED", driver.Doris: "BIGINT", driver.PostgreSQL: "BIGINT",
+		driver.SQLite: "INTEGER", driver.MsSQL: "BIGINT", driver.ClickHouse: "Int64",
+	},
+	"float": {
+		driver.MySQL: "DOUBLE", driver.Doris: "DOUBLE", driver.PostgreSQL: "DOUBLE PRECISION",
+		driver.SQLite: "REAL", driver.MsSQL: "FLOAT", driver.ClickHouse: "Float64",
+	},
+	"date": {
+		driver.MySQL: "DATE", driver.Doris: "DATE", driver.PostgreSQL: "DATE",
+		driver.SQLite: "TEXT", driver.MsSQL: "DATE", driver.ClickHouse: "Date",
+	},
+	"datetime": {
+		driver.MySQL: "DATETIME", driver.Doris: "DATETIME", driver.PostgreSQL: "TIMESTAMP",
+		driver.SQLite: "TEXT", driver.MsSQL: "DATETIME2", driver.ClickHouse: "DateTime",
+	},
+}
+
+// truncLayouts are the layouts of DateFormat that DateTrunc formats with on MySQL, Doris and SQLite
+var truncLayouts = map[string]string{
+	"year":   "%Y-01-01 00:00:00",
+	"month":  "%Y-%m-01 00:00:00",
+	"day":    "%Y-%m-%d 00:00:00",
+	"hour":   "%Y-%m-%d %H:00:00",
+	"minute": "%Y-%m-%d %H:%M:00",
+}
+
+// dateTokens are the tokens of a DateFormat layout in each dialect
+var dateTokens = map[byte]map[driver.Typ]string{
+	'Y': {driver.PostgreSQL: "YYYY", driver.MsSQL: "yyyy"},
+	'm': {driver.PostgreSQL: "MM", driver.MsSQL: "MM"},
+	'd': {driver.PostgreSQL: "DD", driver.MsSQL: "dd"},
+	'H': {driver.PostgreSQL: "HH24", driver.MsSQL: "HH"},
+	'M': {driver.PostgreSQL: "MI", driver.MsSQL: "mm", driver.MySQL: "%i", driver.Doris: "%i", driver.ClickHouse: "%i"},
+	'S': {driver.PostgreSQL: "SS", driver.MsSQL: "ss"},
+}
+
+// Case returns a searched CASE, the conditions of When are expressions of the Cond of the statement
+func Case() Expr {
+	return Expr{kind: exprCase}
+}
+
+// CaseOf returns a simple CASE comparing value with the values of When
+func CaseOf(value interface{}) Expr {
+	return Expr{kind: exprCase, value: value, simple: true}
+}
+
+// When adds a WHEN ... THEN to the CASE
+func (e Expr) When(when, then interface{}) Expr {
+	e.whens = append(e.whens[:len(e.whens):len(e.whens)], exprWhen{when: when, then: then})
+	return e
+}
+
+// Else sets the ELSE of the CASE
+func (e Expr) Else(value interface{}) Expr {
+	e.orElse, e.hasElse = value, true
+	return e
+}
+
+// Coalesce returns the first of values that is not NULL
+func Coalesce(values ...interface{}) Expr {
+	return Expr{kind: exprCoalesce, args: values}
+}
+
+// NullIf returns NULL when value equals other, otherwise value
+func NullIf(value, other interface{}) Expr {
+	return Expr{kind: exprNullIf, args: []interface{}{value, other}}
+}
+
+// Cast converts value to typ, the portable types string, int, float, date and datetime
+// are written in the type of the dialect, any other typ is written as it is
+func Cast(value interface{}, typ string) Expr {
+	return Expr{kind: exprCast, value: value, layout: typ}
+}
+
+// Concat joins values as strings, with || on SQLite and CONCAT otherwise
+func Concat(values ...interface{}) Expr {
+	return Expr{kind: exprConcat, args: values}
+}
+
+// DateTrunc truncates the time value to the start of unit, which is one of
+// year, month, day, hour and minute
+func DateTrunc(unit string, value interface{}) Expr {
+	e := Expr{kind: exprDateTrunc, value: value, layout: strings.ToLower(unit)}
+	if _, ok := truncLayouts[e.layout]; !ok {
+		e.err = fmt.Errorf("expr error: unsupported DateTrunc unit %q", unit)
+	}
+	return e
+}
+
+// DateFormat formats the time value by layout, the tokens %Y, %m, %d, %H, %M (minute), %S and %%
+// are written in the format of the dialect, other text is kept as it is
+func DateFormat(value interface{}, layout string) Expr {
+	e := Expr{kind: exprDateFormat, value: value, layout: layout}
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			continue
+		}
+		if i++; i == len(layout) || (layout[i] != '%' && dateTokens[layout[i]] == nil) {
+			e.err = fmt.Errorf("expr error: unsupported DateFormat layout %q", layout)
+			break
+		}
+	}
+	return e
+}
+
+func (e Expr) build(w *exprWriter) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	switch e.kind {
+	case exprCase:
+		if len(e.whens) == 0 {
+			return errors.New("expr error: CASE requires When")
+		}
+		w.write("CASE")
+		if e.simple {
+			w.write(" ")
+			w.operand(e.value)
+		}
+		for _, when := range e.whens {
+			w.write(" WHEN ")
+			if s, ok := when.when.(string); ok && !e.simple {
+				w.cond(s)
+			} else {
+				w.operand(when.when)
+			}
+			w.write(" THEN ")
+			w.operand(when.then)
+		}
+		if e.hasElse {
+			w.write(" ELSE ")
+			w.operand(e.orElse)
+		}
+		w.write(" END")
+	case exprCoalesce:
+		w.call("COALESCE", e.args)
+	case exprNullIf:
+		w.call("NULLIF", e.args)
+	case exprCast:
+		typ := e.layout
+		if types, ok := castTypes[strings.ToLower(typ)]; ok {
+			typ = types[w.d]
+		}
+		if typ == "" {
+			return fmt.Errorf("expr error: unsupported Cast type %q for %s", e.layout, w.d)
+		}
+		w.write("CAST(")
+		w.operand(e.value)
+		w.write(" AS " + typ + ")")
+	case exprConcat:
+		if w.d != driver.SQLite {
+			w.call("CONCAT", e.args)
+			break
+		}
+		w.write("(")
+		for i := range e.args {
+			if i > 0 {
+				w.write(" || ")
+			}
+			w.operand(e.args[i])
+		}
+		w.write(")")
+	case exprDateTrunc:
+		w.dateTrunc(e.layout, e.value)
+	case exprDateFormat:
+		w.dateFormat(e.value, e.layout)
+	}
+	return nil
+}
+
+func (w *exprWriter) write(s string) {
+	w.buf.WriteString(s)
+}
+
+// operand writes v through the compile handler of the statement
+func (w *exprWriter) operand(v interface{}) {
+	w.values, _ = w.handle(w.buf, w.values, v)
+}
+
+// cond writes a condition built by the Cond of the statement
+func (w *exprWriter) cond(s string) {
+	var sql string
+	sql, w.values = w.compile(s, w.values)
+	w.buf.WriteString(sql)
+}
+
+func (w *exprWriter) call(fn string, args []interface{}) {
+	w.write(fn + "(")
+	for i := range args {
+		if i > 0 {
+			w.write(", ")
+		}
+		w.operand(args[i])
+	}
+	w.write(")")
+}
+
+func (w *exprWriter) dateTrunc(unit string, value interface{}) {
+	switch w.d {
+	case driver.PostgreSQL:
+		w.write("date_trunc('" + unit + "', ")
+		w.operand(value)
+		w.write(")")
+	case driver.MsSQL:
+		w.write("DATEADD(" + unit + ", DATEDIFF(" + unit + ", 0, ")
+		w.operand(value)
+		w.write("), 0)")
+	case driver.ClickHouse:
+		w.write("toStartOf" + strings.ToUpper(unit[:1]) + unit[1:] + "(")
+		w.operand(value)
+		w.write(")")
+	case driver.SQLite:
+		w.dateFormat(value, truncLayouts[unit])
+	default:
+		w.write("CAST(")
+		w.dateFormat(value, truncLayouts[unit])
+		w.write(" AS DATETIME)")
+	}
+}
+
+func (w *exprWriter) dateFormat(value interface{}, layout string) {
+	switch w.d {
+	case driver.SQLite:
+		w.write("strftime(")
+		w.operand(layout)
+		w.write(", ")
+		w.operand(value)
+		w.write(")")
+		return
+	case driver.PostgreSQL:
+		w.write("to_char(")
+	case driver.MsSQL:
+		w.write("FORMAT(")
+	case driver.ClickHouse:
+		w.write("formatDateTime(")
+	default:
+		w.write("DATE_FORMAT(")
+	}
+	w.operand(value)
+	w.write(", ")
+	w.operand(dateLayout(w.d, layout))
+	w.write(")")
+}
+
+// dateLayout converts a DateFormat layout to the format of the dialect,
+// the literal text is quoted for PostgreSQL and escaped for MSSQL
+func dateLayout(d driver.Typ, layout string) string {
+	var buf strings.Builder
+	for i := 0; i < len(layout); i++ {
+		ch := layout[i]
+		if ch == '%' && i+1 < len(layout) {
+			i++
+			if layout[i] == '%' {
+				ch = '%'
+			} else {
+				if s, ok := dateTokens[layout[i]][d]; ok {
+					buf.WriteString(s)
+				} else {
+					buf.WriteString("%" + string(layout[i]))
+				}
+				continue
+			}
+		}
+		switch {
+		case d == driver.PostgreSQL && (isLetter(ch) || ch == '"'):
+			if ch == '"' {
+				buf.WriteString(`\"`)
+			} else {
+				buf.WriteString(`"` + string(ch) + `"`)
+			}
+		case d == driver.MsSQL && (isLetter(ch) || ch == '\\' || ch == '%' || ch == '\'' || ch == '"'):
+			buf.WriteString(`\` + string(ch))
+		case ch == '%' && d != driver.PostgreSQL && d != driver.MsSQL:
+			buf.WriteString("%%")
+		default:
+			buf.WriteByte(ch)
+		}
+	}
+	return buf.String()
+}
+
+func isLetter(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
@@ -0,0 +1,113 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver"
+	"github.com/zlsgo/zdb/driver/mssql"
+	"github.com/zlsgo/zdb/driver/mysql"
+	"github.com/zlsgo/zdb/driver/postgres"
+)
+
+func TestExprCase(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sb := builder.Query("user").SetDriver(&postgres.Config{})
+	level := builder.Case().When(sb.Cond.GE("score", 90), "A").When(sb.Cond.GE("score", 60), "B").Else("C")
+	name := builder.Coalesce(builder.Column("nickname"), builder.Column("name"), "anonymous")
+	sb.Select("id", sb.As(sb.Cond.Var(level), "level"), sb.As(sb.Cond.Var(name), "name"))
+	sb.Where(sb.Cond.Var(builder.NullIf(builder.Column("status"), 0)) + " IS NOT NULL")
+	sb.OrderBy(sb.Cond.Var(builder.CaseOf(builder.Column("role")).When("admin", 0).Else(1)), "id DESC")
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT "id", CASE WHEN "score" >= $1 THEN $2 WHEN "score" >= $3 THEN $4 ELSE $5 END AS level, COALESCE("nickname", "name", $6) AS name FROM "user" WHERE NULLIF("status", $7) IS NOT NULL ORDER BY CASE "role" WHEN $8 THEN $9 ELSE $10 END, id DESC`, sql)
+	tt.Equal([]interface{}{90, "A", 60, "B", "C", "anonymous", 0, "admin", 0, 1}, values)
+
+	sql, _, err = builder.Render(sb, driver.MySQL)
+	tt.NoError(err)
+	tt.Equal("SELECT `id`, CASE WHEN `score` >= ? THEN ? WHEN `score` >= ? THEN ? ELSE ? END AS level, COALESCE(`nickname`, `name`, ?) AS name FROM `user` WHERE NULLIF(`status`, ?) IS NOT NULL ORDER BY CASE `role` WHEN ? THEN ? ELSE ? END, id DESC", sql)
+
+	ub := builder.Update("user")
+	ub.Set(ub.Assign("grade", builder.Case().When(ub.Cond.GT("score", 60), "pass").Else("fail")))
+	ub.Where(ub.Cond.EQ("id", 1))
+	sql, values, err = ub.Build()
+	tt.NoError(err)
+	tt.Equal(`UPDATE "user" SET "grade" = CASE WHEN "score" > ? THEN ? ELSE ? END WHERE "id" = ?`, sql)
+	tt.Equal([]interface{}{60, "pass", "fail", 1}, values)
+
+	sb = builder.Query("user")
+	sb.Select(sb.Cond.Var(builder.Case()))
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+}
+
+func TestExprFunctions(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	build := func(b *builder.SelectBuilder, e builder.Expr) string {
+		sql, _, err := b.Select(b.Cond.Var(e)).Build()
+		tt.NoError(err)
+		return sql
+	}
+	name := builder.Concat(builder.Column("first"), " ", builder.Column("last"))
+
+	tt.Equal(`SELECT CAST("age" AS TEXT) FROM "t"`, build(builder.Query("t"), builder.Cast(builder.Column("age"), "string")))
+	tt.Equal("SELECT CAST(`age` AS SIGNED) FROM `t`", build(builder.Query("t").SetDriver(&mysql.Config{}), builder.Cast(builder.Column("age"), "int")))
+	tt.Equal(`SELECT CAST("age" AS NUMERIC(10, 2)) FROM "t"`, build(builder.Query("t").SetDriver(&postgres.Config{}), builder.Cast(builder.Column("age"), "NUMERIC(10, 2)")))
+
+	tt.Equal(`SELECT ("first" || ? || "last") FROM "t"`, build(builder.Query("t"), name))
+	tt.Equal(`SELECT CONCAT("first", $1, "last") FROM "t"`, build(builder.Query("t").SetDriver(&postgres.Config{}), name))
+
+	day := builder.DateTrunc("day", builder.Column("created_at"))
+	tt.Equal(`SELECT strftime(?, "created_at") FROM "t"`, build(builder.Query("t"), day))
+	tt.Equal(`SELECT date_trunc('day', "created_at") FROM "t"`, build(builder.Query("t").SetDriver(&postgres.Config{}), day))
+	tt.Equal("SELECT CAST(DATE_FORMAT(`created_at`, ?) AS DATETIME) FROM `t`", build(builder.Query("t").SetDriver(&mysql.Config{}), day))
+	tt.Equal(`SELECT DATEADD(day, DATEDIFF(day, 0, "created_at"), 0) FROM "t"`, build(builder.Query("t").SetDriver(&mssql.Config{}), day))
+
+	layout := builder.DateFormat(builder.Column("created_at"), "%Y-%m-%d %H:%M at %S%%")
+	for _, c := range []struct {
+		b      *builder.SelectBuilder
+		sql    string
+		layout string
+	}{
+		{builder.Query("t"), `SELECT strftime(?, "created_at") FROM "t"`, "%Y-%m-%d %H:%M at %S%%"},
+		{builder.Query("t").SetDriver(&mysql.Config{}), "SELECT DATE_FORMAT(`created_at`, ?) FROM `t`", "%Y-%m-%d %H:%i at %S%%"},
+		{builder.Query("t").SetDriver(&postgres.Config{}), `SELECT to_char("created_at", $1) FROM "t"`, `YYYY-MM-DD HH24:MI "a""t" SS%`},
+		{builder.Query("t").SetDriver(&mssql.Config{}), `SELECT FORMAT("created_at", @p1) FROM "t"`, `yyyy-MM-dd HH:mm \a\t ss\%`},
+	} {
+		sql, values, err := c.b.Select(c.b.Cond.Var(layout)).Build()
+		tt.NoError(err)
+		tt.Equal(c.sql, sql)
+		tt.Equal([]interface{}{c.layout}, values)
+	}
+
+	sb := builder.Query("t")
+	_, _, err := sb.Select(sb.Cond.Var(builder.DateTrunc("week", "x"))).Build()
+	tt.EqualTrue(err != nil)
+	sb = builder.Query("t")
+	_, _, err = sb.Select(sb.Cond.Var(builder.DateFormat("x", "%Q"))).Build()
+	tt.EqualTrue(err != nil)
+}
+
+func TestExprNestedError(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sub := builder.Query("order").Select("user_id")
+	sub.Where(sub.Cond.EQ("created_at", builder.DateTrunc("week", "now")))
+	sb := builder.Query("user")
+	sb.Where(sb.Cond.InQuery("id", sub))
+	_, _, err := sb.Build()
+	tt.EqualTrue(err != nil)
+
+	b := builder.Query("b")
+	b.Select(b.Cond.Var(builder.DateFormat("x", "%Q")))
+	_, _, err = builder.UnionAll(builder.Query("c").Select("x"), b).Build()
+	tt.EqualTrue(err != nil)
+
+	u := builder.UnionAll(builder.Query("c").Select("x"), builder.Query("d").Select("x"))
+	u.Except(b)
+	_, _, err = u.Build()
+	tt.EqualTrue(err != nil)
+}
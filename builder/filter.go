@@ -0,0 +1,458 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// FilterRule whitelists the columns, operators and sort fields a client filter may use
+	FilterRule struct {
+		// Columns maps the filterable columns to their allowed operators, no operators allows all
+		Columns map[string][]string
+		// Sorts lists the columns allowed in sort
+		Sorts []string
+		// MaxDepth limits the nesting of and/or groups, defaults to 3
+		MaxDepth int
+		// MaxSize caps the page size, defaults to 100
+		MaxSize int
+		// DefaultSize is the page size used when size is not given, defaults to 20
+		DefaultSize int
+	}
+	// Filter is a validated client filter, Apply writes it to a SelectBuilder
+	Filter struct {
+		cond filterNode
+		// orders holds pairs of column and direction
+		orders []string
+		Page   int
+		Size   int
+	}
+	filterNode struct {
+		value    interface{}
+		field    string
+		op       string
+		children []filterNode
+		or       bool
+	}
+)
+
+// FilterOperators lists the operators of a client filter
+var FilterOperators = []string{"eq", "ne", "gt", "gte", "lt", "lte", "like", "nlike", "in", "nin", "between", "null"}
+
+// maxFilterOffset caps the offset of a filter page, larger pages are rejected
+const maxFilterOffset = math.MaxInt32
+
+var filterOps = map[string]string{
+	"eq": "=", "ne": "!=", "gt": ">", "gte": ">=", "lt": "<", "lte": "<=",
+	"like": "like", "nlike": "not like", "in": "in", "nin": "not in", "between": "between", "null": "null",
+}
+
+// ParseQuery parses a query string such as
+// filter[age][gte]=18&filter[or][0][name][like]=%a%&sort=-created_at&page=2&size=20,
+// a repeated key such as filter[id]=1&filter[id]=2 gives a list, which eq and ne compare as in and nin
+func (r *FilterRule) ParseQuery(values url.Values) (*Filter, error) {
+	filter := map[string]interface{}{}
+	maxSegments := r.maxDepth()*2 + 2
+	for key, vals := range values {
+		if !strings.HasPrefix(key, "filter[") {
+			continue
+		}
+		path, err := filterPath(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > maxSegments {
+			return nil, fmt.Errorf("filter error: %s is nested too deep", key)
+		}
+
+		var leaf interface{}
+		if len(vals) == 1 {
+			leaf = vals[0]
+		} else {
+			list := make([]interface{}, len(vals))
+			for i := range vals {
+				list[i] = vals[i]
+			}
+			leaf = list
+		}
+
+		node := filter
+		for i, seg := range path {
+			if i == len(path)-1 {
+				if _, ok := node[seg].(map[string]interface{}); ok {
+					return nil, fmt.Errorf("filter error: %s conflicts with another filter", key)
+				}
+				node[seg] = leaf
+				break
+			}
+			child, ok := node[seg].(map[string]interface{})
+			if !ok {
+				if _, exists := node[seg]; exists {
+					return nil, fmt.Errorf("filter error: %s conflicts with another filter", key)
+				}
+				child = map[string]interface{}{}
+				node[seg] = child
+			}
+			node = child
+		}
+	}
+
+	var sorts []string
+	for _, s := range values["sort"] {
+		sorts = append(sorts, strings.Split(s, ",")...)
+	}
+	return r.parse(filter, sorts, values.Get("page"), values.Get("size"))
+}
+
+// ParseJSON parses a filter body such as
+// {"filter": {"age": {"gte": 18}, "or": [{"name": {"like": "%a%"}}]}, "sort": ["-created_at"], "page": 2, "size": 20}
+func (r *FilterRule) ParseJSON(data []byte) (*Filter, error) {
+	var body struct {
+		Filter map[string]interface{} `json:"filter"`
+		Sort   interface{}            `json:"sort"`
+		Page   json.Number            `json:"page"`
+		Size   json.Number            `json:"size"`
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&body); err != nil {
+		return nil, fmt.Errorf("filter error: %v", err)
+	}
+
+	var sorts []string
+	switch v := body.Sort.(type) {
+	case nil:
+	case string:
+		sorts = strings.Split(v, ",")
+	case []interface{}:
+		for i := range v {
+			s, ok := v[i].(string)
+			if !ok {
+				return nil, fmt.Errorf("filter error: invalid sort %v", v[i])
+			}
+			sorts = append(sorts, s)
+		}
+	default:
+		return nil, fmt.Errorf("filter error: invalid sort %v", v)
+	}
+	return r.parse(body.Filter, sorts, body.Page.String(), body.Size.String())
+}
+
+func (r *FilterRule) parse(filter map[string]interface{}, sorts []string, page, size string) (*Filter, error) {
+	f := &Filter{Page: 1, Size: r.DefaultSize}
+	if f.Size <= 0 {
+		f.Size = 20
+	}
+
+	var err error
+	if f.cond, err = r.parseGroup(filter, false, 1); err != nil {
+		return nil, err
+	}
+
+	for _, s := range sorts {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		order := "ASC"
+		if s[0] == '-' {
+			s, order = s[1:], "DESC"
+		} else if s[0] == '+' {
+			s = s[1:]
+		}
+		if !containsString(r.Sorts, s) {
+			return nil, fmt.Errorf("filter error: sort by %s is not allowed", s)
+		}
+		f.orders = append(f.orders, s, order)
+	}
+
+	if page != "" {
+		if f.Page, err = strconv.Atoi(page); err != nil || f.Page < 1 {
+			return nil, fmt.Errorf("filter error: invalid page %s", page)
+		}
+	}
+	if size != "" {
+		if f.Size, err = strconv.Atoi(size); err != nil || f.Size < 1 {
+			return nil, fmt.Errorf("filter error: invalid size %s", size)
+		}
+	}
+	if maxSize := r.MaxSize; maxSize <= 0 && f.Size > 100 {
+		f.Size = 100
+	} else if maxSize > 0 && f.Size > maxSize {
+		f.Size = maxSize
+	}
+	if _, err = f.offset(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (r *FilterRule) maxDepth() int {
+	if r.MaxDepth <= 0 {
+		return 3
+	}
+	return r.MaxDepth
+}
+
+// parseGroup validates the conditions of a group, each key is a column or an and/or group
+func (r *FilterRule) parseGroup(m map[string]interface{}, or bool, depth int) (filterNode, error) {
+	group := filterNode{or: or}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := m[key]
+		if key == "or" || key == "and" {
+			if depth >= r.maxDepth() {
+				return group, fmt.Errorf("filter error: groups are nested deeper than %d", r.maxDepth())
+			}
+			node, err := r.parseNested(value, key == "or", depth+1)
+			if err != nil {
+				return group, err
+			}
+			group.children = append(group.children, node)
+			continue
+		}
+
+		ops, ok := r.Columns[key]
+		if !ok {
+			return group, fmt.Errorf("filter error: column %s is not allowed", key)
+		}
+		conds, ok := value.(map[string]interface{})
+		if !ok {
+			conds = map[string]interface{}{"eq": value}
+		}
+		opKeys := make([]string, 0, len(conds))
+		for op := range conds {
+			opKeys = append(opKeys, op)
+		}
+		sort.Strings(opKeys)
+		for _, op := range opKeys {
+			node, err := filterCond(key, op, conds[op], ops)
+			if err != nil {
+				return group, err
+			}
+			group.children = append(group.children, node)
+		}
+	}
+	return group, nil
+}
+
+// parseNested validates an and/or group given as a list of groups or as a map of conditions,
+// query strings give lists as maps with index keys
+func (r *FilterRule) parseNested(value interface{}, or bool, depth int) (filterNode, error) {
+	var items []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		items = v
+	case map[string]interface{}:
+		indexes := make([]int, 0, len(v))
+		for k := range v {
+			i, err := strconv.Atoi(k)
+			if err != nil {
+				indexes = nil
+				break
+			}
+			indexes = append(indexes, i)
+		}
+		if indexes == nil {
+			return r.parseGroup(v, or, depth)
+		}
+		sort.Ints(indexes)
+		for _, i := range indexes {
+			items = append(items, v[strconv.Itoa(i)])
+		}
+	default:
+		return filterNode{}, fmt.Errorf("filter error: invalid group %v", value)
+	}
+
+	group := filterNode{or: or}
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return group, fmt.Errorf("filter error: invalid group %v", item)
+		}
+		node, err := r.parseGroup(m, false, depth)
+		if err != nil {
+			return group, err
+		}
+		group.children = append(group.children, node)
+	}
+	return group, nil
+}
+
+func filterCond(field, op string, value interface{}, allowed []string) (filterNode, error) {
+	if _, ok := value.([]interface{}); ok {
+		switch op {
+		case "eq":
+			op = "in"
+		case "ne":
+			op = "nin"
+		}
+	}
+	sqlOp, ok := filterOps[op]
+	if !ok || (len(allowed) > 0 && !containsString(allowed, op)) {
+		return filterNode{}, fmt.Errorf("filter error: operator %s is not allowed on %s", op, field)
+	}
+
+	value = filterValue(value)
+	switch op {
+	case "in", "nin", "between":
+		if s, ok := value.(string); ok {
+			parts := strings.Split(s, ",")
+			list := make([]interface{}, len(parts))
+			for i := range parts {
+				list[i] = parts[i]
+			}
+			value = list
+		}
+		list, ok := value.([]interface{})
+		if !ok || len(list) == 0 || (op == "between" && len(list) != 2) {
+			return filterNode{}, fmt.Errorf("filter error: invalid value of %s on %s", op, field)
+		}
+	case "null":
+		switch v := value.(type) {
+		case bool:
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return filterNode{}, fmt.Errorf("filter error: invalid value of %s on %s", op, field)
+			}
+			value = b
+		default:
+			return filterNode{}, fmt.Errorf("filter error: invalid value of %s on %s", op, field)
+		}
+	default:
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return filterNode{}, fmt.Errorf("filter error: invalid value of %s on %s", op, field)
+		}
+	}
+	return filterNode{field: field, op: sqlOp, value: value}, nil
+}
+
+// filterValue converts JSON numbers into int64 or float64
+func filterValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i := range v {
+			list[i] = filterValue(v[i])
+		}
+		return list
+	}
+	return value
+}
+
+// filterPath splits "filter[a][b][c]" into its segments
+func filterPath(key string) ([]string, error) {
+	var path []string
+	s := key[len("filter"):]
+	for s != "" {
+		end := strings.IndexByte(s, ']')
+		if s[0] != '[' || end < 2 {
+			return nil, fmt.Errorf("filter error: invalid key %s", key)
+		}
+		path = append(path, s[1:end])
+		s = s[end+1:]
+	}
+	return path, nil
+}
+
+func containsString(list []string, s string) bool {
+	for i := range list {
+		if list[i] == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply writes the conditions, orders and the page of the filter to b,
+// it matches the callback of DB.Find
+func (f *Filter) Apply(b *SelectBuilder) error {
+	exprs, err := f.cond.exprs(b.Cond)
+	if err != nil {
+		return err
+	}
+	b.Where(exprs...)
+	for i := 0; i < len(f.orders); i += 2 {
+		b.OrderBy(b.Cond.quoteField(f.orders[i]) + " " + f.orders[i+1])
+	}
+	if f.Size > 0 {
+		offset, err := f.offset()
+		if err != nil {
+			return err
+		}
+		b.Limit(f.Size).Offset(offset)
+	}
+	return nil
+}
+
+// offset returns the offset of the page, pages past maxFilterOffset are rejected
+func (f *Filter) offset() (int, error) {
+	if f.Page < 1 || f.Size < 1 {
+		return 0, nil
+	}
+	if f.Page-1 > maxFilterOffset/f.Size {
+		return 0, fmt.Errorf("filter error: page %d is out of range", f.Page)
+	}
+	return (f.Page - 1) * f.Size, nil
+}
+
+func (n *filterNode) build(c *BuildCond) (string, error) {
+	if n.field != "" {
+		if n.op == "null" {
+			if n.value.(bool) {
+				return c.IsNull(n.field), nil
+			}
+			return c.IsNotNull(n.field), nil
+		}
+		return c.compare(n.field, n.op, n.value)
+	}
+
+	exprs, err := n.exprs(c)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case len(exprs) == 0:
+		return "", nil
+	case len(exprs) == 1:
+		return exprs[0], nil
+	case n.or:
+		return c.Or(exprs...), nil
+	}
+	return c.And(exprs...), nil
+}
+
+func (n *filterNode) exprs(c *BuildCond) ([]string, error) {
+	exprs := make([]string, 0, len(n.children))
+	for i := range n.children {
+		expr, err := n.children[i].build(c)
+		if err != nil {
+			return nil, err
+		}
+		if expr != "" {
+			exprs = append(exprs, expr)
+		}
+	}
+	return exprs, nil
+}
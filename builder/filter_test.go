@@ -0,0 +1,130 @@
+package builder_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver/postgres"
+)
+
+var testFilterRule = &builder.FilterRule{
+	Columns: map[string][]string{
+		"age":        {"gt", "gte", "lt", "lte", "between"},
+		"name":       {"eq", "like"},
+		"status":     nil,
+		"deleted_at": {"null"},
+	},
+	Sorts:   []string{"created_at", "id"},
+	MaxSize: 50,
+}
+
+func TestFilterParseQuery(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	values, _ := url.ParseQuery("filter[age][gte]=18&filter[status][in]=1,2&filter[deleted_at][null]=true" +
+		"&filter[or][0][name][like]=%25a%25&filter[or][1][name]=bob&filter[or][1][age][lt]=10" +
+		"&sort=-created_at,id&page=2&size=20")
+	f, err := testFilterRule.ParseQuery(values)
+	tt.NoError(err)
+	tt.Equal(2, f.Page)
+	tt.Equal(20, f.Size)
+
+	sb := builder.Query("user").SetDriver(&postgres.Config{})
+	tt.NoError(f.Apply(sb))
+	sql, values2, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" WHERE "age" >= $1 AND "deleted_at" IS NULL AND ("name" LIKE $2 OR ("age" < $3 AND "name" = $4)) AND "status" IN ($5, $6) ORDER BY "created_at" DESC, "id" ASC LIMIT 20 OFFSET 20`, sql)
+	tt.Equal([]interface{}{"18", "%a%", "10", "bob", "1", "2"}, values2)
+
+	values, _ = url.ParseQuery("filter[status][eq]=1&filter[status][eq]=2&filter[status][ne]=3&filter[status][ne]=4")
+	f, err = testFilterRule.ParseQuery(values)
+	tt.NoError(err)
+	sb = builder.Query("user")
+	tt.NoError(f.Apply(sb))
+	sql, values2, err = sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" WHERE "status" IN (?, ?) AND "status" NOT IN (?, ?) LIMIT 20`, sql)
+	tt.Equal([]interface{}{"1", "2", "3", "4"}, values2)
+
+	values, _ = url.ParseQuery("filter[status]=1&filter[status]=2")
+	f, err = testFilterRule.ParseQuery(values)
+	tt.NoError(err)
+	sb = builder.Query("user")
+	tt.NoError(f.Apply(sb))
+	tt.Equal(`SELECT * FROM "user" WHERE "status" IN (1, 2) LIMIT 20`, sb.String())
+
+	values, _ = url.ParseQuery("filter[name]=a&filter[name]=b")
+	_, err = testFilterRule.ParseQuery(values)
+	tt.EqualTrue(err != nil)
+
+	values, _ = url.ParseQuery("size=1000")
+	f, err = testFilterRule.ParseQuery(values)
+	tt.NoError(err)
+	tt.Equal(1, f.Page)
+	tt.Equal(50, f.Size)
+}
+
+func TestFilterParseJSON(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	f, err := testFilterRule.ParseJSON([]byte(`{
+		"filter": {"age": {"between": [18, 30]}, "or": {"name": "bob", "status": {"nin": [3, 4]}}},
+		"sort": ["id"],
+		"page": 3
+	}`))
+	tt.NoError(err)
+
+	sb := builder.Query("user")
+	tt.NoError(f.Apply(sb))
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" WHERE "age" BETWEEN ? AND ? AND ("name" = ? OR "status" NOT IN (?, ?)) ORDER BY "id" ASC LIMIT 20 OFFSET 40`, sql)
+	tt.Equal([]interface{}{int64(18), int64(30), "bob", int64(3), int64(4)}, values)
+}
+
+func TestFilterWhitelist(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	for _, q := range []string{
+		"filter[password]=1",
+		"filter[name][gt]=a",
+		"filter[age][regexp]=1",
+		"filter[age][between]=1",
+		"filter[deleted_at][null]=maybe",
+		"filter[name]]=a",
+		"filter[name][eq][x]=a",
+		"sort=password",
+		"page=0",
+		"page=9223372036854775807&size=50",
+		"page=42949674&size=50",
+		"size=abc",
+		"filter[or][0][and][0][or][0][name]=a",
+	} {
+		values, _ := url.ParseQuery(q)
+		_, err := testFilterRule.ParseQuery(values)
+		tt.Log(q, err)
+		tt.EqualTrue(err != nil)
+	}
+
+	for _, body := range []string{
+		`{"filter": {"name": {"like": ["a"]}}}`,
+		`{"filter": {"or": [1]}}`,
+		`{"filter": {"and": {"and": {"and": {"name": "a"}}}}}`,
+		`{"sort": 1}`,
+		`{"filter": []}`,
+	} {
+		_, err := testFilterRule.ParseJSON([]byte(body))
+		tt.Log(body, err)
+		tt.EqualTrue(err != nil)
+	}
+
+	f := &builder.Filter{Page: 1 << 40, Size: 100}
+	tt.EqualTrue(f.Apply(builder.Query("user")) != nil)
+
+	rule := &builder.FilterRule{Columns: map[string][]string{"name": nil}, MaxDepth: 5}
+	values, _ := url.ParseQuery("filter[or][0][and][0][or][0][name]=a")
+	_, err := rule.ParseQuery(values)
+	tt.NoError(err)
+}
@@ -3,6 +3,7 @@ package builder
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/sohaha/zlsgo/zutil"
 	"github.com/zlsgo/zdb/driver"
@@ -16,6 +17,10 @@ type InsertBuilder struct {
 	cols    []string
 	values  [][]string
 	options [][]string
+	query   *SelectBuilder
+	with    withClause
+	// queryVar is the placeholder the SELECT is compiled from
+	queryVar string
 }
 
 var _ Builder = new(InsertBuilder)
@@ -50,6 +55,18 @@ func InsertIgnore(table string) *InsertBuilder {
 	}
 }
 
+// With adds a common table expression before the INSERT, cols optionally names its columns
+func (b *InsertBuilder) With(name string, builder Builder, cols ...string) *InsertBuilder {
+	b.with.add(false, name, builder, cols)
+	return b
+}
+
+// WithRecursive adds a recursive common table expression before the INSERT
+func (b *InsertBuilder) WithRecursive(name string, builder Builder, cols ...string) *InsertBuilder {
+	b.with.add(true, name, builder, cols)
+	return b
+}
+
 // Cols sets columns in INSERT
 func (b *InsertBuilder) Cols(col ...string) *InsertBuilder {
 	b.cols = EscapeAll(col...)
@@ -68,6 +85,14 @@ func (b *InsertBuilder) Values(v ...interface{}) *InsertBuilder {
 	return b
 }
 
+// Select inserts the rows returned by query instead of VALUES,
+// its arguments are merged into the INSERT and follow its placeholder numbering
+func (b *InsertBuilder) Select(query *SelectBuilder) *InsertBuilder {
+	b.query = query
+	b.queryVar = b.cond.Var(query)
+	return b
+}
+
 func (b *InsertBuilder) Option(opt ...string) *InsertBuilder {
 	b.options = append(b.options, opt)
 	return b
@@ -81,11 +106,17 @@ func (b *InsertBuilder) String() string {
 
 // Build returns compiled INSERT string and Cond
 func (b *InsertBuilder) Build() (sql string, values []interface{}, err error) {
+	if err = b.check(); err != nil {
+		return "", nil, err
+	}
 	sql, values = b.build(false)
+	if b.cond.err != nil {
+		return "", nil, b.cond.err
+	}
 	return
 }
 
-func (b *InsertBuilder) build(blend bool) (sql string, args []interface{}) {
+func (b *InsertBuilder) build(blend bool, initial ...interface{}) (sql string, args []interface{}) {
 	// More accurate size estimation
 	estimatedSize := len(b.verb) + 6  // verb + " INTO "
 	estimatedSize += len(b.table) + 4 // table + quotes
@@ -116,19 +147,24 @@ func (b *InsertBuilder) build(blend bool) (sql string, args []interface{}) {
 		}
 	}
 
+	prefix, initial := b.with.build(b.cond, blend, initial)
+
 	buf := zutil.GetBuff(uint(estimatedSize))
 	defer zutil.PutBuff(buf)
 
 	driverValue := b.cond.driver.Value()
 
+	// MySQL only accepts WITH in front of the SELECT of INSERT
+	mysqlWith := prefix != "" && (driverValue == driver.MySQL || driverValue == driver.Doris)
+
 	buf.WriteString(b.verb)
 	buf.WriteString(" INTO ")
-	buf.WriteString(driverValue.Quote(b.table))
+	buf.WriteString(b.cond.quote(b.table))
 
 	if len(b.cols) > 0 {
 		buf.WriteString(" (")
 
-		quotedCols := driverValue.QuoteCols(b.cols)
+		quotedCols := b.cond.quoteCols(b.cols)
 		for i, col := range quotedCols {
 			if i > 0 {
 				buf.WriteString(", ")
@@ -139,7 +175,18 @@ func (b *InsertBuilder) build(blend bool) (sql string, args []interface{}) {
 		buf.WriteString(")")
 	}
 
-	buf.WriteString(" VALUES ")
+	if mysqlWith {
+		buf.WriteRune(' ')
+		buf.WriteString(strings.TrimSuffix(prefix, " "))
+		prefix = ""
+	}
+
+	if b.query != nil {
+		buf.WriteRune(' ')
+		buf.WriteString(b.buildQuery(driverValue))
+	} else {
+		buf.WriteString(" VALUES ")
+	}
 
 	for i, v := range b.values {
 		if i > 0 {
@@ -176,10 +223,11 @@ func (b *InsertBuilder) build(blend bool) (sql string, args []interface{}) {
 	}
 
 	if blend {
-		return b.cond.CompileString(buf.String()), nil
+		return prefix + b.cond.CompileString(buf.String(), initial...), nil
 	}
 
-	return b.cond.Compile(buf.String())
+	sql, args = b.cond.Compile(buf.String(), initial...)
+	return prefix + sql, args
 }
 
 // SetDriver Set the compilation statements driver
@@ -201,6 +249,9 @@ func (b *InsertBuilder) Safety() error {
 	if len(b.cols) == 0 {
 		return errors.New("insert safety error: no columns specified")
 	}
+	if b.query != nil {
+		return b.checkQuery()
+	}
 	if len(b.values) == 0 {
 		return errors.New("insert safety error: no values specified")
 	}
@@ -221,3 +272,43 @@ func (b *InsertBuilder) BatchValues(values [][]interface{}) *InsertBuilder {
 	}
 	return b
 }
+
+func (b *InsertBuilder) nestedBuild(parent *BuildCond, blend bool, initial []interface{}) (string, []interface{}, error) {
+	c := b.Clone()
+	c.cond.inherit(parent)
+	if err := c.check(); err != nil {
+		return "", initial, err
+	}
+	sql, values := c.build(blend, initial...)
+	return sql, values, c.cond.err
+}
+
+// check returns the errors that keep the INSERT from being run
+func (b *InsertBuilder) check() error {
+	if d := b.cond.driver.Value(); len(b.with.ctes) > 0 && b.query == nil && (d == driver.MySQL || d == driver.Doris) {
+		return errors.New("insert error: WITH requires Select on MySQL")
+	}
+	if b.query != nil {
+		return b.checkQuery()
+	}
+	return nil
+}
+
+func (b *InsertBuilder) checkQuery() error {
+	if len(b.values) > 0 {
+		return errors.New("insert error: Select cannot be combined with Values")
+	}
+	if len(b.query.tables) == 0 || b.query.tables[0] == "" {
+		return errors.New("insert error: select has no table")
+	}
+	return b.query.check()
+}
+
+// buildQuery returns the placeholder of the SELECT, SQLite needs a WHERE
+// in front of ON CONFLICT to tell the upsert clause from a join constraint
+func (b *InsertBuilder) buildQuery(driverValue driver.Typ) string {
+	if driverValue == driver.SQLite && len(b.options) > 0 && len(b.query.whereExprs) == 0 {
+		return "SELECT * FROM (" + b.queryVar + ") WHERE true"
+	}
+	return b.queryVar
+}
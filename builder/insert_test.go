@@ -8,6 +8,7 @@ import (
 	"github.com/zlsgo/zdb/builder"
 	"github.com/zlsgo/zdb/driver/mysql"
 	"github.com/zlsgo/zdb/driver/postgres"
+	"github.com/zlsgo/zdb/driver/sqlite3"
 )
 
 func TestInsert(t *testing.T) {
@@ -168,3 +169,42 @@ func TestInsertBatchValues(t *testing.T) {
 	tt.Equal("INSERT INTO `user` (`username`, `age`) VALUES (?, ?), (?, ?), (?, ?)", sql)
 	tt.Equal([]interface{}{"user1", 18, "user2", 25, "user3", 30}, values)
 }
+
+func TestInsertSelect(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	pg := &postgres.Config{}
+	src := builder.Query("user").SetDriver(pg).Select("id", "name")
+	src.Where(src.Cond.LT("created_at", 100), src.Cond.EQ("status", 1))
+
+	ib := builder.Insert("user_archive").SetDriver(pg).Cols("id", "name").Select(src)
+	ib.Option("ON CONFLICT (id) DO UPDATE SET name = " + ib.Var("x"))
+	sql, values, err := ib.Build()
+	tt.NoError(err)
+	tt.Equal(`INSERT INTO "user_archive" ("id", "name") SELECT "id", "name" FROM "user" WHERE "created_at" < $1 AND "status" = $2 ON CONFLICT (id) DO UPDATE SET name = $3`, sql)
+	tt.Equal([]interface{}{100, 1, "x"}, values)
+	tt.NoError(ib.Safety())
+
+	src = builder.Query("user").SetDriver(&mysql.Config{}).Select("id")
+	src.Where(src.Cond.GT("id", 10))
+	ib = builder.InsertIgnore("user_archive").SetDriver(&mysql.Config{}).Cols("id").Select(src)
+	sql, values, err = ib.Build()
+	tt.NoError(err)
+	tt.Equal("INSERT IGNORE INTO `user_archive` (`id`) SELECT `id` FROM `user` WHERE `id` > ?", sql)
+	tt.Equal([]interface{}{10}, values)
+
+	ib = builder.Insert("user_archive").SetDriver(&sqlite3.Config{}).Cols("id").
+		Select(builder.Query("user").Select("id")).Option("ON CONFLICT DO NOTHING")
+	sql, _, err = ib.Build()
+	tt.NoError(err)
+	tt.Equal(`INSERT INTO "user_archive" ("id") SELECT * FROM (SELECT "id" FROM "user") WHERE true ON CONFLICT DO NOTHING`, sql)
+
+	_, _, err = builder.Insert("user_archive").Cols("id").Select(builder.Query("")).Build()
+	tt.EqualTrue(err != nil)
+
+	_, _, err = builder.Insert("user_archive").Cols("id").Values(1).Select(builder.Query("user")).Build()
+	tt.EqualTrue(err != nil)
+
+	_, _, err = builder.Insert("user_archive").Cols("id").Select(builder.Query("user").WhereBy(1)).Build()
+	tt.EqualTrue(err != nil)
+}
@@ -0,0 +1,168 @@
+package builder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/zlsgo/zdb/driver"
+)
+
+const (
+	CrossJoin  JoinOption = "CROSS"
+	CrossApply JoinOption = "CROSS APPLY"
+	OuterApply JoinOption = "OUTER APPLY"
+)
+
+var (
+	errLateralUnsupported = errors.New("select error: LATERAL requires MySQL 8.0.14")
+	errApplyOn            = errors.New("select error: APPLY does not accept ON expressions, move them into the subquery")
+)
+
+// CrossJoin adds a CROSS JOIN of table in SELECT
+func (b *SelectBuilder) CrossJoin(table string) *SelectBuilder {
+	return b.JoinWithOption(CrossJoin, table)
+}
+
+// JoinUsing adds a JOIN of table matching the columns of the same name
+func (b *SelectBuilder) JoinUsing(table string, cols ...string) *SelectBuilder {
+	return b.JoinUsingWithOption("", table, cols...)
+}
+
+// JoinUsingWithOption adds a JOIN of table with an option matching the columns of the same name,
+// it is not supported by MSSQL
+func (b *SelectBuilder) JoinUsingWithOption(option JoinOption, table string, cols ...string) *SelectBuilder {
+	b.JoinWithOption(option, table)
+	b.joinUsing[len(b.joinUsing)-1] = cols
+	return b
+}
+
+// JoinSub adds a JOIN of the result of the subquery sub named alias, its arguments are merged into the SELECT
+func (b *SelectBuilder) JoinSub(option JoinOption, sub Builder, alias string, onExpr ...string) *SelectBuilder {
+	return b.JoinWithOption(option, b.BuilderAs(sub, alias), onExpr...)
+}
+
+// JoinLateral adds a JOIN of the subquery sub named alias that may refer to the tables before it.
+// Without ON expressions an inner join is written as CROSS JOIN LATERAL and a left join gets ON TRUE,
+// MSSQL writes them as CROSS APPLY and OUTER APPLY which accept no ON expressions.
+// It requires PostgreSQL, MySQL 8.0.14 or MSSQL
+func (b *SelectBuilder) JoinLateral(option JoinOption, sub Builder, alias string, onExpr ...string) *SelectBuilder {
+	b.JoinWithOption(option, b.BuilderAs(sub, alias), onExpr...)
+	b.joinLateral[len(b.joinLateral)-1] = true
+	return b
+}
+
+// joinOuter reports whether the lateral join i keeps the rows without a match
+func (b *SelectBuilder) joinOuter(i int) bool {
+	switch b.joinOptions[i] {
+	case LeftJoin, LeftOuterJoin, OuterApply:
+		return true
+	}
+	return false
+}
+
+// checkJoins rejects the joins the dialect does not support
+func (b *SelectBuilder) checkJoins() error {
+	d := b.Cond.driver.Value()
+	for i := range b.joinTables {
+		option, on := b.joinOptions[i], b.joinExprs[i]
+		switch {
+		case option == FullJoin || option == FullOuterJoin:
+			if d == driver.MySQL {
+				return fmt.Errorf("select error: %s JOIN is not supported by %s", option, d)
+			}
+		case option == CrossJoin:
+			if len(on) > 0 || len(b.joinUsing[i]) > 0 {
+				return errors.New("select error: CROSS JOIN does not accept ON or USING")
+			}
+		}
+
+		if len(b.joinUsing[i]) > 0 && d == driver.MsSQL {
+			return fmt.Errorf("select error: JOIN USING is not supported by %s", d)
+		}
+
+		if !b.joinLateral[i] {
+			continue
+		}
+		switch option {
+		case "", InnerJoin, LeftJoin, LeftOuterJoin, CrossJoin:
+		case CrossApply, OuterApply:
+			if len(on) > 0 {
+				return errApplyOn
+			}
+		default:
+			return fmt.Errorf("select error: %s JOIN LATERAL is not supported", option)
+		}
+		switch d {
+		case driver.PostgreSQL:
+		case driver.MySQL:
+			if driver.VersionBelow(b.Cond.driver, "8.0.14") {
+				return errLateralUnsupported
+			}
+		case driver.MsSQL:
+			if len(on) > 0 {
+				return errApplyOn
+			}
+		default:
+			return fmt.Errorf("select error: LATERAL is not supported by %s", d)
+		}
+	}
+	return nil
+}
+
+// buildJoin writes the join i of SELECT
+func (b *SelectBuilder) buildJoin(buf *bytes.Buffer, d driver.Typ, i int) {
+	table, on := b.joinTables[i], b.joinExprs[i]
+	if b.Cond.strict {
+		table = b.Cond.quote(table)
+	}
+
+	if b.joinLateral[i] {
+		outer := b.joinOuter(i)
+		switch {
+		case d == driver.MsSQL && outer:
+			buf.WriteString(" OUTER APPLY ")
+		case d == driver.MsSQL:
+			buf.WriteString(" CROSS APPLY ")
+		case outer:
+			buf.WriteString(" LEFT JOIN LATERAL ")
+		case len(on) == 0:
+			buf.WriteString(" CROSS JOIN LATERAL ")
+		default:
+			buf.WriteString(" JOIN LATERAL ")
+		}
+		buf.WriteString(table)
+		if outer && len(on) == 0 && d != driver.MsSQL {
+			buf.WriteString(" ON TRUE")
+		}
+	} else {
+		if option := b.joinOptions[i]; option != "" {
+			buf.WriteRune(' ')
+			buf.WriteString(string(option))
+		}
+		buf.WriteString(" JOIN ")
+		buf.WriteString(table)
+		buf.WriteString(b.lockHint(d, table))
+	}
+
+	if cols := b.joinUsing[i]; len(cols) > 0 {
+		buf.WriteString(" USING (")
+		for j, col := range b.Cond.quoteCols(cols) {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(col)
+		}
+		buf.WriteString(")")
+	}
+
+	if len(on) > 0 {
+		buf.WriteString(" ON ")
+		for j, expr := range on {
+			if j > 0 {
+				buf.WriteString(" AND ")
+			}
+			buf.WriteString(expr)
+		}
+	}
+}
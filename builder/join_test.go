@@ -0,0 +1,88 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver"
+	"github.com/zlsgo/zdb/driver/mssql"
+	"github.com/zlsgo/zdb/driver/mysql"
+	"github.com/zlsgo/zdb/driver/postgres"
+)
+
+func TestSelectJoinKinds(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sb := builder.Query("user u").SetDriver(&postgres.Config{})
+	sb.CrossJoin("region r").JoinUsingWithOption(builder.LeftJoin, "profile", "user_id", "tenant_id")
+	sb.Where(sb.Cond.EQ("u.status", 1))
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" u CROSS JOIN region r LEFT JOIN profile USING ("user_id", "tenant_id") WHERE "u"."status" = $1`, sql)
+	tt.Equal([]interface{}{1}, values)
+
+	_, _, err = builder.Query("user").SetDriver(&mssql.Config{}).JoinUsing("profile", "user_id").Build()
+	tt.EqualTrue(err != nil)
+	_, _, err = builder.Query("user").JoinWithOption(builder.CrossJoin, "profile", "a = b").Build()
+	tt.EqualTrue(err != nil)
+	_, _, err = builder.Query("a").SetDriver(&mysql.Config{}).JoinWithOption(builder.FullJoin, "b", "a.id = b.id").Build()
+	tt.EqualTrue(err != nil)
+}
+
+func TestSelectJoinSub(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sub := builder.Query("order").Select("user_id", "sum(amount) AS total").GroupBy("user_id")
+	sub.Where(sub.Cond.GT("amount", 10))
+
+	sb := builder.Query("user u").SetDriver(&postgres.Config{})
+	sb.Where(sb.Cond.EQ("u.status", 1))
+	sb.JoinSub(builder.LeftJoin, sub, "o", "o.user_id = u.id", sb.Cond.GT("o.total", 100))
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" u LEFT JOIN (SELECT "user_id", sum(amount) AS total FROM "order" WHERE "amount" > $1 GROUP BY user_id) AS o ON o.user_id = u.id AND "o"."total" > $2 WHERE "u"."status" = $3`, sql)
+	tt.Equal([]interface{}{10, 100, 1}, values)
+}
+
+func TestSelectJoinLateral(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	latest := func(d driver.Dialect) *builder.SelectBuilder {
+		sub := builder.Query("order o").SetDriver(d).Select("o.amount")
+		sub.Where("o.user_id = u.id", sub.Cond.GT("o.amount", 0)).OrderBy("o.id DESC").Limit(1)
+		return sub
+	}
+
+	sb := builder.Query("user u").SetDriver(&postgres.Config{})
+	sb.JoinLateral(builder.InnerJoin, latest(&postgres.Config{}), "l")
+	sb.Where(sb.Cond.EQ("u.status", 1))
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" u CROSS JOIN LATERAL (SELECT "o"."amount" FROM "order" o WHERE o.user_id = u.id AND "o"."amount" > $1 ORDER BY o.id DESC LIMIT 1) AS l WHERE "u"."status" = $2`, sql)
+	tt.Equal([]interface{}{0, 1}, values)
+
+	sb = builder.Query("user u").SetDriver(&mysql.Config{Version: "8.0.36"})
+	sb.JoinLateral(builder.LeftJoin, latest(&mysql.Config{}), "l")
+	sql, _, err = sb.Build()
+	tt.NoError(err)
+	tt.Equal("SELECT * FROM `user` u LEFT JOIN LATERAL (SELECT `o`.`amount` FROM `order` o WHERE o.user_id = u.id AND `o`.`amount` > ? ORDER BY o.id DESC LIMIT 1) AS l ON TRUE", sql)
+
+	sb = builder.Query("user u").SetDriver(&mssql.Config{})
+	sb.JoinLateral(builder.OuterApply, builder.Query("order o").Select("o.amount"), "l")
+	sql, _, err = sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" u OUTER APPLY (SELECT "o"."amount" FROM "order" o) AS l`, sql)
+
+	sb = builder.Query("user u").SetDriver(&mssql.Config{})
+	sb.JoinLateral(builder.LeftJoin, latest(builder.DefaultDriver), "l", "l.amount > 1")
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+
+	_, _, err = builder.Query("user u").SetDriver(&mysql.Config{Version: "8.0.13"}).JoinLateral("", latest(builder.DefaultDriver), "l").Build()
+	tt.EqualTrue(err != nil)
+	_, _, err = builder.Query("user u").JoinLateral("", latest(builder.DefaultDriver), "l").Build()
+	tt.EqualTrue(err != nil)
+	_, _, err = builder.Query("user u").SetDriver(&postgres.Config{}).JoinLateral(builder.RightJoin, latest(builder.DefaultDriver), "l", "true").Build()
+	tt.EqualTrue(err != nil)
+}
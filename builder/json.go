@@ -0,0 +1,289 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zdb/driver"
+)
+
+type (
+	jsonKind uint8
+	// jsonPathSegment is a key of a JSON path, index is set for a number in brackets
+	jsonPathSegment struct {
+		key   string
+		index bool
+	}
+	// jsonArgs is a JSON path expression, it is written when the statement is
+	// compiled so that it follows the dialect and the placeholders of the statement
+	jsonArgs struct {
+		value interface{}
+		field string
+		path  string
+		alias string
+		kind  jsonKind
+	}
+)
+
+const (
+	jsonExtract jsonKind = iota
+	jsonEQ
+	jsonContains
+	jsonHasKey
+)
+
+// JSONExtract returns the text of the scalar at path of the JSON column field,
+// path is like "a.b[0]" or "$.a.b[0]"
+func (c *BuildCond) JSONExtract(field, path string) string {
+	return c.Var(jsonArgs{kind: jsonExtract, field: field, path: path})
+}
+
+// JSONEQ represents "the scalar at path of field = value", a nil value compiles to IS NULL
+func (c *BuildCond) JSONEQ(field, path string, value interface{}) string {
+	return c.Var(jsonArgs{kind: jsonEQ, field: field, path: path, value: value})
+}
+
+// JSONContains represents that the JSON value at path of field contains value, such as an element of an array,
+// SQLite, MSSQL and ClickHouse only match the scalar elements of an array
+func (c *BuildCond) JSONContains(field, path string, value interface{}) string {
+	return c.Var(jsonArgs{kind: jsonContains, field: field, path: path, value: value})
+}
+
+// JSONHasKey represents that path exists in the JSON column field, SQL Server requires 2022
+func (c *BuildCond) JSONHasKey(field, path string) string {
+	return c.Var(jsonArgs{kind: jsonHasKey, field: field, path: path})
+}
+
+// JSONField returns the scalar at path of the JSON column field as a column of SELECT,
+// the alias is quoted like a column when the statement is compiled
+func (b *SelectBuilder) JSONField(field, path, alias string) string {
+	if alias == "" {
+		return "(" + b.Cond.JSONExtract(field, path) + ")"
+	}
+	return b.Cond.Var(jsonArgs{kind: jsonExtract, field: field, path: path, alias: alias})
+}
+
+// build writes the expression on field, the column quoted by the Cond of the statement
+func (a jsonArgs) build(
+	buf *bytes.Buffer,
+	d driver.Typ,
+	field string,
+	values []interface{},
+	write func(*bytes.Buffer, driver.Typ, []interface{}, interface{}) []interface{},
+) []interface{} {
+	bind := func(v interface{}) {
+		values = write(buf, d, values, v)
+	}
+
+	switch a.kind {
+	case jsonContains:
+		a.buildContains(buf, d, field, bind)
+		return values
+	case jsonHasKey:
+		switch d {
+		case driver.PostgreSQL:
+			buf.WriteString("(" + field + "::jsonb #> ")
+			bind(pgJSONPath(a.path))
+			buf.WriteString("::text[]) IS NOT NULL")
+		case driver.SQLite:
+			buf.WriteString("json_type(" + field + ", ")
+			bind(jsonPath(a.path))
+			buf.WriteString(") IS NOT NULL")
+		case driver.MsSQL:
+			buf.WriteString("JSON_PATH_EXISTS(" + field + ", ")
+			bind(jsonPath(a.path))
+			buf.WriteString(") = 1")
+		case driver.ClickHouse:
+			buf.WriteString("JSONHas(" + field)
+			bindClickHouseKeys(buf, a.path, bind)
+			buf.WriteString(") = 1")
+		default:
+			buf.WriteString("JSON_CONTAINS_PATH(" + field + ", 'one', ")
+			bind(jsonPath(a.path))
+			buf.WriteString(") = 1")
+		}
+		return values
+	}
+
+	switch d {
+	case driver.PostgreSQL:
+		buf.WriteString("(" + field + " #>> ")
+		bind(pgJSONPath(a.path))
+		buf.WriteString("::text[])")
+	case driver.SQLite:
+		buf.WriteString("json_extract(" + field + ", ")
+		bind(jsonPath(a.path))
+		buf.WriteRune(')')
+	case driver.MsSQL, driver.ClickHouse:
+		buf.WriteString("JSON_VALUE(" + field + ", ")
+		bind(jsonPath(a.path))
+		buf.WriteRune(')')
+	default:
+		buf.WriteString("JSON_UNQUOTE(JSON_EXTRACT(" + field + ", ")
+		bind(jsonPath(a.path))
+		buf.WriteString("))")
+	}
+
+	if a.kind == jsonEQ {
+		if a.value == nil {
+			buf.WriteString(" IS NULL")
+		} else {
+			buf.WriteString(" = ")
+			bind(jsonText(d, a.value))
+		}
+	}
+	return values
+}
+
+func (a jsonArgs) buildContains(buf *bytes.Buffer, d driver.Typ, field string, bind func(interface{})) {
+	switch d {
+	case driver.PostgreSQL:
+		if keys := jsonPathKeys(a.path); len(keys) > 0 {
+			buf.WriteString("(" + field + "::jsonb #> ")
+			bind(pgJSONPath(a.path))
+			buf.WriteString("::text[])")
+		} else {
+			buf.WriteString(field + "::jsonb")
+		}
+		buf.WriteString(" @> ")
+		bind(jsonDoc(a.value))
+		buf.WriteString("::jsonb")
+	case driver.SQLite:
+		buf.WriteString("EXISTS (SELECT 1 FROM json_each(" + field + ", ")
+		bind(jsonPath(a.path))
+		buf.WriteString(") WHERE value = ")
+		bind(a.value)
+		buf.WriteRune(')')
+	case driver.MsSQL:
+		buf.WriteString("EXISTS (SELECT 1 FROM OPENJSON(" + field + ", ")
+		bind(jsonPath(a.path))
+		buf.WriteString(") WHERE value = ")
+		bind(jsonText(d, a.value))
+		buf.WriteRune(')')
+	case driver.ClickHouse:
+		buf.WriteString("has(JSONExtractArrayRaw(" + field)
+		bindClickHouseKeys(buf, a.path, bind)
+		buf.WriteString("), ")
+		bind(jsonDoc(a.value))
+		buf.WriteRune(')')
+	default:
+		buf.WriteString("JSON_CONTAINS(" + field + ", ")
+		bind(jsonDoc(a.value))
+		buf.WriteString(", ")
+		bind(jsonPath(a.path))
+		buf.WriteRune(')')
+	}
+}
+
+// jsonText converts value into the text the dialect extracts from JSON,
+// SQLite keeps the SQL type of the scalar
+func jsonText(d driver.Typ, value interface{}) interface{} {
+	if d == driver.SQLite {
+		return value
+	}
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case string:
+		return v
+	}
+	if d == driver.MsSQL {
+		return ztype.ToString(value)
+	}
+	return value
+}
+
+// jsonDoc encodes value as a JSON document, json.RawMessage is kept as is
+func jsonDoc(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return ztype.ToString(value)
+	}
+	return string(b)
+}
+
+// jsonPath returns path as an SQL/JSON path starting with $
+func jsonPath(path string) string {
+	path = strings.TrimSpace(path)
+	switch {
+	case path == "":
+		return "$"
+	case path[0] == '$':
+		return path
+	case path[0] == '[':
+		return "$" + path
+	}
+	return "$." + path
+}
+
+// pgJSONPath returns path as the text array used by the PostgreSQL #> and #>> operators
+func pgJSONPath(path string) string {
+	keys := jsonPathKeys(path)
+	for i := range keys {
+		keys[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(keys[i]) + `"`
+	}
+	return "{" + strings.Join(keys, ",") + "}"
+}
+
+// bindClickHouseKeys writes the keys of path as the arguments of a ClickHouse JSON function,
+// whose array indexes start at 1
+func bindClickHouseKeys(buf *bytes.Buffer, path string, bind func(interface{})) {
+	for _, seg := range jsonPathSegments(path) {
+		buf.WriteString(", ")
+		if i, err := strconv.Atoi(seg.key); err == nil && seg.index {
+			bind(i + 1)
+			continue
+		}
+		bind(seg.key)
+	}
+}
+
+// jsonPathKeys splits a path such as $.a."b.c"[0] into its keys and indexes
+func jsonPathKeys(path string) []string {
+	segs := jsonPathSegments(path)
+	keys := make([]string, len(segs))
+	for i := range segs {
+		keys[i] = segs[i].key
+	}
+	return keys
+}
+
+func jsonPathSegments(path string) []jsonPathSegment {
+	p := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	keys := make([]jsonPathSegment, 0, strings.Count(p, ".")+strings.Count(p, "["))
+	for len(p) > 0 {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+		case '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				end = len(p)
+			}
+			key := strings.Trim(p[1:end], `"'`)
+			keys = append(keys, jsonPathSegment{key: key, index: key == p[1:end]})
+			p = p[min(end+1, len(p)):]
+		case '"':
+			end := strings.IndexByte(p[1:], '"') + 1
+			if end <= 0 {
+				end = len(p)
+			}
+			keys = append(keys, jsonPathSegment{key: p[1:end]})
+			p = p[min(end+1, len(p)):]
+		default:
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			keys = append(keys, jsonPathSegment{key: p[:end]})
+			p = p[end:]
+		}
+	}
+	return keys
+}
@@ -0,0 +1,104 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver"
+	"github.com/zlsgo/zdb/driver/mssql"
+	"github.com/zlsgo/zdb/driver/mysql"
+	"github.com/zlsgo/zdb/driver/postgres"
+	"github.com/zlsgo/zdb/driver/sqlite3"
+)
+
+func TestJSONCond(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	build := func(d driver.Dialect) (string, []interface{}) {
+		sb := builder.Query("user").SetDriver(d)
+		sb.Select("id", sb.JSONField("profile", "address.city", "city"))
+		sb.Where(
+			sb.Cond.EQ("id", 1),
+			sb.Cond.JSONEQ("profile", "age", 18),
+			sb.Cond.JSONContains("profile", "$.tags", "go"),
+			sb.Cond.JSONHasKey("profile", "vip"),
+		)
+		sql, values, err := sb.Build()
+		tt.NoError(err)
+		return sql, values
+	}
+
+	sql, values := build(&mysql.Config{})
+	tt.Equal("SELECT `id`, (JSON_UNQUOTE(JSON_EXTRACT(`profile`, ?))) AS `city` FROM `user` WHERE `id` = ? AND JSON_UNQUOTE(JSON_EXTRACT(`profile`, ?)) = ? AND JSON_CONTAINS(`profile`, ?, ?) AND JSON_CONTAINS_PATH(`profile`, 'one', ?) = 1", sql)
+	tt.Equal([]interface{}{"$.address.city", 1, "$.age", 18, `"go"`, "$.tags", "$.vip"}, values)
+
+	sql, values = build(&postgres.Config{})
+	tt.Equal(`SELECT "id", (("profile" #>> $1::text[])) AS "city" FROM "user" WHERE "id" = $2 AND ("profile" #>> $3::text[]) = $4 AND ("profile"::jsonb #> $5::text[]) @> $6::jsonb AND ("profile"::jsonb #> $7::text[]) IS NOT NULL`, sql)
+	tt.Equal([]interface{}{`{"address","city"}`, 1, `{"age"}`, 18, `{"tags"}`, `"go"`, `{"vip"}`}, values)
+
+	sql, values = build(&sqlite3.Config{})
+	tt.Equal(`SELECT "id", (json_extract("profile", ?)) AS "city" FROM "user" WHERE "id" = ? AND json_extract("profile", ?) = ? AND EXISTS (SELECT 1 FROM json_each("profile", ?) WHERE value = ?) AND json_type("profile", ?) IS NOT NULL`, sql)
+	tt.Equal([]interface{}{"$.address.city", 1, "$.age", 18, "$.tags", "go", "$.vip"}, values)
+
+	sql, values = build(&mssql.Config{})
+	tt.Equal(`SELECT "id", (JSON_VALUE("profile", @p1)) AS "city" FROM "user" WHERE "id" = @p2 AND JSON_VALUE("profile", @p3) = @p4 AND EXISTS (SELECT 1 FROM OPENJSON("profile", @p5) WHERE value = @p6) AND JSON_PATH_EXISTS("profile", @p7) = 1`, sql)
+	tt.Equal([]interface{}{"$.address.city", 1, "$.age", "18", "$.tags", "go", "$.vip"}, values)
+}
+
+func TestJSONCondClickHouse(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sb := builder.Query("user")
+	sb.Where(
+		sb.Cond.JSONContains("profile", "$.tags", "go"),
+		sb.Cond.JSONHasKey("profile", `a.b[2]."3"`),
+	)
+	sql, values, err := builder.Render(sb, driver.ClickHouse)
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" WHERE has(JSONExtractArrayRaw("profile", ?), ?) AND JSONHas("profile", ?, ?, ?, ?) = 1`, sql)
+	tt.Equal([]interface{}{"tags", `"go"`, "a", "b", 3, "3"}, values)
+}
+
+func TestJSONCondValues(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sb := builder.Query("user").SetDriver(&postgres.Config{})
+	sb.Where(
+		sb.Cond.JSONEQ("data", `$.items[0]."a.b"`, true),
+		sb.Cond.JSONEQ("data", "deleted", nil),
+		sb.Cond.JSONContains("data", "", map[string]interface{}{"role": "admin"}),
+	)
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" WHERE ("data" #>> $1::text[]) = $2 AND ("data" #>> $3::text[]) IS NULL AND "data"::jsonb @> $4::jsonb`, sql)
+	tt.Equal([]interface{}{`{"items","0","a.b"}`, "true", `{"deleted"}`, `{"role":"admin"}`}, values)
+
+	sb = builder.Query("user").SetDriver(&mysql.Config{})
+	sb.Where(sb.Cond.JSONContains("data", "", []int{1, 2}), sb.Cond.JSONEQ("data", "[1]", false))
+	sql, values, err = sb.Build()
+	tt.NoError(err)
+	tt.Equal("SELECT * FROM `user` WHERE JSON_CONTAINS(`data`, ?, ?) AND JSON_UNQUOTE(JSON_EXTRACT(`data`, ?)) = ?", sql)
+	tt.Equal([]interface{}{"[1,2]", "$", "$[1]", "false"}, values)
+}
+
+func TestJSONFieldAlias(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sb := builder.Query("user").SetDriver(&mysql.Config{}).SetStrict(true)
+	sb.Select(sb.JSONField("profile", "order", "order"))
+	sql, _, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal("SELECT (JSON_UNQUOTE(JSON_EXTRACT(`profile`, ?))) AS `order` FROM `user`", sql)
+
+	sb = builder.Query("user").SetDriver(&mysql.Config{}).SetStrict(true)
+	sb.Select(sb.JSONField("profile", "city", "city` FROM `admin` -- "))
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+
+	sb = builder.Query("user")
+	sb.Select(sb.JSONField("profile", "city", "city"))
+	sql, _, err = builder.Render(sb, driver.MySQL)
+	tt.NoError(err)
+	tt.Equal("SELECT (JSON_UNQUOTE(JSON_EXTRACT(`profile`, ?))) AS `city` FROM `user`", sql)
+}
@@ -0,0 +1,131 @@
+package builder
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zlsgo/zdb/driver"
+)
+
+// rowLock is the locking clause of SELECT, MSSQL writes it as table hints
+// and SQLite, which locks the whole database, ignores it
+type rowLock struct {
+	mode    string
+	wait    string
+	tables  []string
+	timeout time.Duration
+}
+
+var errLockUnsupported = errors.New("select error: NOWAIT, SKIP LOCKED and OF require MySQL 8.0")
+
+// SkipLocked skips the rows locked by other transactions, READPAST on MSSQL
+func (b *SelectBuilder) SkipLocked() *SelectBuilder {
+	b.lock.wait = "SKIP LOCKED"
+	return b
+}
+
+// NoWait fails at once instead of waiting for locked rows, NOWAIT on MSSQL
+func (b *SelectBuilder) NoWait() *SelectBuilder {
+	b.lock.wait = "NOWAIT"
+	return b
+}
+
+// Of limits the lock to tables, which are table names or aliases of the query
+func (b *SelectBuilder) Of(tables ...string) *SelectBuilder {
+	b.lock.tables = append(b.lock.tables, tables...)
+	return b
+}
+
+// LockTimeout sets how long the SELECT waits for locked rows, it is set by
+// LockTimeoutStatements before the query, DB rejects it outside a transaction
+func (b *SelectBuilder) LockTimeout(timeout time.Duration) *SelectBuilder {
+	b.lock.timeout = timeout
+	return b
+}
+
+// LockTimeoutStatements returns the statements that set the lock wait timeout
+// before the SELECT and reset it after, reset is empty when the setting ends with the transaction.
+// MySQL keeps the previous timeout in the session variable @zdb_lock_wait_timeout to restore it
+func (b *SelectBuilder) LockTimeoutStatements() (set, reset string) {
+	if b.lock.mode == "" || b.lock.timeout <= 0 {
+		return "", ""
+	}
+	ms := b.lock.timeout.Milliseconds()
+	switch b.Cond.driver.Value() {
+	case driver.MySQL:
+		seconds := (ms + 999) / 1000
+		return "SET @zdb_lock_wait_timeout = @@SESSION.innodb_lock_wait_timeout, SESSION innodb_lock_wait_timeout = " + strconv.FormatInt(seconds, 10),
+			"SET SESSION innodb_lock_wait_timeout = @zdb_lock_wait_timeout"
+	case driver.PostgreSQL:
+		return "SET LOCAL lock_timeout = '" + strconv.FormatInt(ms, 10) + "ms'", ""
+	case driver.MsSQL:
+		return "SET LOCK_TIMEOUT " + strconv.FormatInt(ms, 10), "SET LOCK_TIMEOUT -1"
+	}
+	return "", ""
+}
+
+// checkLock rejects the lock options MySQL before 8.0 does not understand
+func (b *SelectBuilder) checkLock() error {
+	if b.lock.mode == "" || (b.lock.wait == "" && len(b.lock.tables) == 0) {
+		return nil
+	}
+	if b.Cond.driver.Value() == driver.MySQL && driver.VersionBelow(b.Cond.driver, "8.0") {
+		return errLockUnsupported
+	}
+	return nil
+}
+
+// buildLock returns the locking clause written at the end of SELECT
+func (b *SelectBuilder) buildLock(d driver.Typ) string {
+	if b.lock.mode == "" {
+		return ""
+	}
+	switch d {
+	case driver.SQLite, driver.ClickHouse, driver.MsSQL:
+		return ""
+	case driver.MySQL:
+		if b.lock.mode == "SHARE" && driver.VersionBelow(b.Cond.driver, "8.0") {
+			return " LOCK IN SHARE MODE"
+		}
+	}
+
+	s := " FOR " + b.lock.mode
+	if len(b.lock.tables) > 0 {
+		s += " OF " + strings.Join(b.Cond.quoteCols(EscapeAll(b.lock.tables...)), ", ")
+	}
+	if b.lock.wait != "" {
+		s += " " + b.lock.wait
+	}
+	return s
+}
+
+// lockHint returns the MSSQL table hint of table, empty when it is not locked
+func (b *SelectBuilder) lockHint(d driver.Typ, table string) string {
+	if b.lock.mode == "" || d != driver.MsSQL {
+		return ""
+	}
+	if len(b.lock.tables) > 0 {
+		fields := strings.Fields(table)
+		if len(fields) == 0 {
+			return ""
+		}
+		name, alias := strings.Trim(fields[0], `"[]`), fields[len(fields)-1]
+		if !containsString(b.lock.tables, name) && !containsString(b.lock.tables, alias) {
+			return ""
+		}
+	}
+
+	hints := []string{"UPDLOCK"}
+	if b.lock.mode == "SHARE" {
+		hints[0] = "HOLDLOCK"
+	}
+	switch b.lock.wait {
+	case "SKIP LOCKED":
+		hints = append(hints, "READPAST")
+	case "NOWAIT":
+		hints = append(hints, "NOWAIT")
+	}
+	return " WITH (" + strings.Join(hints, ", ") + ")"
+}
@@ -0,0 +1,463 @@
+package builder
+
+import (
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zlsgo/zdb/driver"
+)
+
+// typDialect is the dialect Render compiles with when the builder uses
+// another one, it only reports its type and assumes the latest server version
+type typDialect struct {
+	driver.Dialect
+	typ driver.Typ
+}
+
+func (d typDialect) Value() driver.Typ {
+	return d.typ
+}
+
+// Render compiles b for the dialect d without changing the dialect of b,
+// the server version of the dialect of b is kept when it is of the same type.
+// Conditions quote their columns when they are created, so the identifiers
+// quoted for the dialect of b are converted to the quotes of d
+func Render(b Builder, d driver.Typ) (sql string, values []interface{}, err error) {
+	var cond *BuildCond
+	switch v := b.(type) {
+	case *SelectBuilder:
+		v = v.Clone()
+		cond, b = v.Cond, v
+	case *UpdateBuilder:
+		v = v.Clone()
+		cond, b = v.Cond, v
+	case *DeleteBuilder:
+		v = v.Clone()
+		cond, b = v.Cond, v
+	case *UnionBuilder:
+		v = v.Clone()
+		cond, b = v.cond, v
+	case *InsertBuilder:
+		v = v.Clone()
+		cond, b = v.cond, v
+	case *compiledBuilder:
+		v = cloneArg(v).(*compiledBuilder)
+		cond, b = v.Cond, v
+	default:
+		return "", nil, fmt.Errorf("render error: %T cannot be rendered", b)
+	}
+
+	var (
+		dialect driver.Dialect = typDialect{typ: d}
+		from    driver.Typ
+	)
+	if cond.driver != nil {
+		if from = cond.driver.Value(); from == d {
+			dialect = cond.driver
+		}
+	}
+	restore := useDriver(cond, dialect)
+	sql, values, err = b.Build()
+	restore()
+	if err != nil {
+		return "", nil, err
+	}
+	return requote(sql, from, d), values, nil
+}
+
+// Inline compiles b for the dialect d with its values written as escaped
+// literals, the SQL can be copied into a client for logs and bug reports
+func Inline(b Builder, d driver.Typ) (string, error) {
+	sql, values, err := Render(b, d)
+	if err != nil {
+		return "", err
+	}
+	return Interpolate(d, sql, values)
+}
+
+// Interpolate replaces the placeholders of the compiled query of the dialect d
+// with values written as literals, quoted strings, identifiers and comments are skipped
+func Interpolate(d driver.Typ, query string, values []interface{}) (string, error) {
+	var (
+		buf   strings.Builder
+		next  int
+		named = map[string]interface{}{}
+	)
+	buf.Grow(len(query) + 16*len(values))
+
+	positional := len(values)
+	for _, v := range values {
+		if arg, ok := v.(sql.NamedArg); ok {
+			named[arg.Name] = arg.Value
+			positional--
+		}
+	}
+
+	literal := func(v interface{}) error {
+		s, err := Literal(d, v)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(s)
+		return nil
+	}
+	value := func(n int) error {
+		if n < 1 || n > positional {
+			return fmt.Errorf("render error: no value for placeholder %d", n)
+		}
+		next = max(next, n)
+		return literal(values[n-1])
+	}
+
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := skipQuoted(d, query, i, ch)
+			buf.WriteString(query[i:end])
+			i = end - 1
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			buf.WriteString(query[i : i+end])
+			i += end - 1
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+			buf.WriteString(query[i : i+end])
+			i += end - 1
+		case ch == '?' && d != driver.PostgreSQL && d != driver.MsSQL:
+			if err := value(next + 1); err != nil {
+				return "", err
+			}
+		case ch == '$' && d == driver.PostgreSQL:
+			n, end := placeholderIndex(query, i+1)
+			if end == i+1 {
+				buf.WriteByte(ch)
+				continue
+			}
+			if err := value(n); err != nil {
+				return "", err
+			}
+			i = end - 1
+		case ch == '@' && d == driver.MsSQL:
+			end := i + 1
+			for end < len(query) && (query[end] == '_' || query[end] == '@' || isIdentChar(query[end])) {
+				end++
+			}
+			name := query[i+1 : end]
+			if n, pEnd := placeholderIndex(query, i+2); strings.HasPrefix(name, "p") && pEnd == end && pEnd > i+2 {
+				if err := value(n); err != nil {
+					return "", err
+				}
+			} else if v, ok := named[name]; ok {
+				if err := literal(v); err != nil {
+					return "", err
+				}
+			} else {
+				buf.WriteString(query[i:end])
+			}
+			i = end - 1
+		default:
+			buf.WriteByte(ch)
+		}
+	}
+
+	if next != positional {
+		return "", fmt.Errorf("render error: %d placeholders for %d values", next, positional)
+	}
+	return buf.String(), nil
+}
+
+// Literal writes value as an SQL literal of the dialect d
+func Literal(d driver.Typ, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case sql.NamedArg:
+		return Literal(d, v.Value)
+	case sqldriver.Valuer:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return "NULL", nil
+		}
+		val, err := v.Value()
+		if err != nil {
+			return "", fmt.Errorf("render error: %v", err)
+		}
+		return Literal(d, val)
+	case time.Time:
+		if d == driver.PostgreSQL {
+			return "'" + v.Format("2006-01-02 15:04:05.999999Z07:00") + "'", nil
+		}
+		return "'" + v.Format("2006-01-02 15:04:05.999999") + "'", nil
+	case []byte:
+		if v == nil {
+			return "NULL", nil
+		}
+		h := hex.EncodeToString(v)
+		switch d {
+		case driver.PostgreSQL:
+			return `'\x` + h + "'::bytea", nil
+		case driver.MsSQL:
+			return "0x" + h, nil
+		case driver.ClickHouse:
+			return "unhex('" + h + "')", nil
+		}
+		return "X'" + h + "'", nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "NULL", nil
+		}
+		return Literal(d, rv.Elem().Interface())
+	case reflect.Bool:
+		if d == driver.SQLite || d == driver.MsSQL {
+			if rv.Bool() {
+				return "1", nil
+			}
+			return "0", nil
+		}
+		if rv.Bool() {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("render error: %v cannot be written as a literal", f)
+		}
+		return strconv.FormatFloat(f, 'g', -1, rv.Type().Bits()), nil
+	case reflect.String:
+		return quoteString(d, rv.String())
+	}
+	return "", fmt.Errorf("render error: %T cannot be written as a literal", value)
+}
+
+// quoteString quotes s as a string literal, MySQL, Doris and ClickHouse
+// also treat backslashes as escapes
+func quoteString(d driver.Typ, s string) (string, error) {
+	switch d {
+	case driver.MySQL, driver.Doris, driver.ClickHouse:
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", "''", "\x00", `\0`, "\n", `\n`, "\r", `\r`, "\x1a", `\Z`).Replace(s) + "'", nil
+	case driver.PostgreSQL:
+		if strings.IndexByte(s, 0) >= 0 {
+			return "", fmt.Errorf("render error: PostgreSQL strings cannot contain NUL")
+		}
+	case driver.MsSQL:
+		return "N'" + strings.ReplaceAll(s, "'", "''") + "'", nil
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'", nil
+}
+
+// skipQuoted returns the end of the quoted text starting at query[start], a doubled
+// quote stays inside and so does a backslash escape in the strings of MySQL, Doris and ClickHouse
+func skipQuoted(d driver.Typ, query string, start int, quote byte) int {
+	backslash := quote == '\'' && (d == driver.MySQL || d == driver.Doris || d == driver.ClickHouse)
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if backslash {
+				i++
+			}
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+// requote converts the identifiers of query quoted for the dialect from to the quotes of to
+func requote(query string, from, to driver.Typ) string {
+	fromQuote, toQuote := identQuote(from), identQuote(to)
+	if fromQuote == toQuote || fromQuote == 0 || toQuote == 0 {
+		return query
+	}
+
+	var buf strings.Builder
+	buf.Grow(len(query))
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch ch {
+		case '\'':
+			end := skipQuoted(from, query, i, ch)
+			buf.WriteString(query[i:end])
+			i = end - 1
+		case fromQuote:
+			end := skipQuoted(from, query, i, ch)
+			ident := query[i+1 : max(end-1, i+1)]
+			ident = strings.ReplaceAll(ident, string([]byte{fromQuote, fromQuote}), string(fromQuote))
+			ident = strings.ReplaceAll(ident, string(toQuote), string([]byte{toQuote, toQuote}))
+			buf.WriteByte(toQuote)
+			buf.WriteString(ident)
+			buf.WriteByte(toQuote)
+			i = end - 1
+		default:
+			buf.WriteByte(ch)
+		}
+	}
+	return buf.String()
+}
+
+// identQuote returns the character the dialect quotes identifiers with
+func identQuote(d driver.Typ) byte {
+	switch d {
+	case driver.MySQL, driver.Doris:
+		return '`'
+	case driver.PostgreSQL, driver.MsSQL, driver.SQLite, driver.ClickHouse:
+		return '"'
+	}
+	return 0
+}
+
+// placeholderIndex parses the digits of query from start, end is start when there is none
+func placeholderIndex(query string, start int) (n, end int) {
+	end = start
+	for end < len(query) && query[end] >= '0' && query[end] <= '9' {
+		end++
+	}
+	n, _ = strconv.Atoi(query[start:end])
+	return
+}
+
+func isIdentChar(ch byte) bool {
+	return ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+// prettyClauses are the keywords Pretty starts a new line with
+var prettyClauses = map[string]bool{
+	"FROM": true, "WHERE": true, "GROUP": true, "HAVING": true, "WINDOW": true, "ORDER": true,
+	"LIMIT": true, "OFFSET": true, "FETCH": true, "FOR": true, "JOIN": true, "LEFT": true,
+	"RIGHT": true, "INNER": true, "FULL": true, "CROSS": true, "UNION": true, "INTERSECT": true,
+	"EXCEPT": true, "SET": true, "VALUES": true, "USING": true, "RETURNING": true,
+}
+
+// Pretty formats a compiled statement over multiple lines, each clause starts a line,
+// AND and OR of the clause are indented and subqueries are indented by their depth
+func Pretty(query string) string {
+	var (
+		buf     strings.Builder
+		parens  []bool
+		prev    string
+		between bool
+	)
+	buf.Grow(len(query) + len(query)/4)
+
+	depth := func() int {
+		n := 0
+		for _, sub := range parens {
+			if sub {
+				n++
+			}
+		}
+		return n
+	}
+	clause := func() bool {
+		return len(parens) == 0 || parens[len(parens)-1]
+	}
+	newline := func(indent int) {
+		s := strings.TrimRight(buf.String(), " ")
+		buf.Reset()
+		buf.WriteString(s)
+		buf.WriteString("\n")
+		buf.WriteString(strings.Repeat("  ", indent))
+	}
+
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := skipQuoted(0, query, i, ch)
+			buf.WriteString(query[i:end])
+			i = end - 1
+			prev = ""
+		case ch == '(':
+			word := strings.ToUpper(nextWord(query, i+1))
+			sub := word == "SELECT" || word == "WITH"
+			parens = append(parens, sub)
+			buf.WriteByte(ch)
+			if sub {
+				newline(depth())
+				for i+1 < len(query) && query[i+1] == ' ' {
+					i++
+				}
+			}
+			prev = ""
+		case ch == ')':
+			if len(parens) > 0 {
+				sub := parens[len(parens)-1]
+				parens = parens[:len(parens)-1]
+				if sub {
+					newline(depth())
+				}
+			}
+			buf.WriteByte(ch)
+			prev = ""
+		case isIdentChar(ch) || ch == '_':
+			end := i
+			for end < len(query) && (isIdentChar(query[end]) || query[end] == '_') {
+				end++
+			}
+			word := query[i:end]
+			upper := strings.ToUpper(word)
+			if (i == 0 || query[i-1] == ' ') && clause() {
+				switch {
+				case upper == "JOIN" && (prev == "LEFT" || prev == "RIGHT" || prev == "INNER" ||
+					prev == "FULL" || prev == "CROSS" || prev == "OUTER"):
+				case prettyClauses[upper] && !strings.HasPrefix(query[end:], "("):
+					if i > 0 {
+						newline(depth())
+					}
+				case upper == "AND" && between:
+					between = false
+				case upper == "AND" || upper == "OR":
+					newline(depth() + 1)
+				case upper == "BETWEEN":
+					between = true
+				}
+			}
+			buf.WriteString(word)
+			prev = upper
+			i = end - 1
+		default:
+			buf.WriteByte(ch)
+		}
+	}
+	return buf.String()
+}
+
+// nextWord returns the word at the start of s[start:] after spaces
+func nextWord(s string, start int) string {
+	for start < len(s) && s[start] == ' ' {
+		start++
+	}
+	end := start
+	for end < len(s) && (isIdentChar(s[end]) || s[end] == '_') {
+		end++
+	}
+	return s[start:end]
+}
@@ -0,0 +1,163 @@
+package builder_test
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver"
+	"github.com/zlsgo/zdb/driver/mysql"
+)
+
+func TestRender(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sub := builder.Query("order").Select("user_id").SetDriver(&mysql.Config{})
+	sub.Where(sub.Cond.GT("amount", 100))
+	sb := builder.Query("user").Select("id").SetDriver(&mysql.Config{})
+	sb.Where(sb.Cond.EQ("name", "a"), sb.Cond.In("id", sub), sb.Cond.JSONEQ("meta", "a", 1)).Limit(10)
+
+	for d, expected := range map[driver.Typ]string{
+		driver.MySQL:      "SELECT `id` FROM `user` WHERE `name` = ? AND `id` IN (SELECT `user_id` FROM `order` WHERE `amount` > ?) AND JSON_UNQUOTE(JSON_EXTRACT(`meta`, ?)) = ? LIMIT 10",
+		driver.PostgreSQL: `SELECT "id" FROM "user" WHERE "name" = $1 AND "id" IN (SELECT "user_id" FROM "order" WHERE "amount" > $2) AND ("meta" #>> $3::text[]) = $4 LIMIT 10`,
+		driver.MsSQL:      `SELECT "id" FROM "user" WHERE "name" = @p1 AND "id" IN (SELECT "user_id" FROM "order" WHERE "amount" > @p2) AND JSON_VALUE("meta", @p3) = @p4 ORDER BY 1 OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY`,
+	} {
+		sql, values, err := builder.Render(sb, d)
+		tt.NoError(err)
+		tt.Equal(expected, sql)
+		tt.Equal(4, len(values))
+	}
+
+	sql, _, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal("SELECT `id` FROM `user` WHERE `name` = ? AND `id` IN (SELECT `user_id` FROM `order` WHERE `amount` > ?) AND JSON_UNQUOTE(JSON_EXTRACT(`meta`, ?)) = ? LIMIT 10", sql)
+
+	_, _, err = builder.Render(builder.CreateTable("t"), driver.MySQL)
+	tt.EqualTrue(err != nil)
+
+	ib := builder.Insert("user").Cols("name").Values("a").SetDriver(&mysql.Config{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _ = builder.Render(ib, driver.PostgreSQL)
+		}()
+	}
+	wg.Wait()
+	sql, _, err = ib.Build()
+	tt.NoError(err)
+	tt.Equal("INSERT INTO `user` (`name`) VALUES (?)", sql)
+}
+
+func TestInline(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sb := builder.Query("user").SetDriver(&mysql.Config{})
+	sb.Where(sb.Cond.EQ("name", `it's \ "a"`), sb.Cond.EQ("ok", true), sb.Cond.IsNull("deleted_at"),
+		sb.Cond.In("id", 1, 2.5), sb.Cond.EQ("raw", builder.Raw("NOW()")), sb.Cond.EQ("q", "?"))
+
+	s, err := builder.Inline(sb, driver.MySQL)
+	tt.NoError(err)
+	tt.Equal("SELECT * FROM `user` WHERE `name` = 'it''s \\\\ \"a\"' AND `ok` = TRUE AND `deleted_at` IS NULL AND `id` IN (1, 2.5) AND `raw` = NOW() AND `q` = '?'", s)
+
+	s, err = builder.Inline(sb, driver.PostgreSQL)
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" WHERE "name" = 'it''s \ "a"' AND "ok" = TRUE AND "deleted_at" IS NULL AND "id" IN (1, 2.5) AND "raw" = NOW() AND "q" = '?'`, s)
+
+	s, err = builder.Inline(sb, driver.MsSQL)
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" WHERE "name" = N'it''s \ "a"' AND "ok" = 1 AND "deleted_at" IS NULL AND "id" IN (1, 2.5) AND "raw" = NOW() AND "q" = N'?'`, s)
+
+	s, err = builder.Interpolate(driver.SQLite, `SELECT '?', "?" FROM t WHERE a = ? AND b = ? -- ?`, []interface{}{[]byte("ab"), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)})
+	tt.NoError(err)
+	tt.Equal(`SELECT '?', "?" FROM t WHERE a = X'6162' AND b = '2024-01-02 03:04:05' -- ?`, s)
+
+	s, err = builder.Interpolate(driver.MsSQL, `SELECT * FROM t WHERE a = @p1 AND b = @name`, []interface{}{1, sql.Named("name", "x")})
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM t WHERE a = 1 AND b = N'x'`, s)
+
+	_, err = builder.Interpolate(driver.MySQL, `SELECT ?`, nil)
+	tt.EqualTrue(err != nil)
+	_, err = builder.Interpolate(driver.MySQL, `SELECT ?`, []interface{}{1, 2})
+	tt.EqualTrue(err != nil)
+	_, err = builder.Interpolate(driver.MySQL, `SELECT ?`, []interface{}{struct{}{}})
+	tt.EqualTrue(err != nil)
+}
+
+func TestPretty(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sub := builder.Query("order").Select("user_id")
+	sub.Where(sub.Cond.GT("amount", 100), sub.Cond.Between("day", 1, 7))
+	sb := builder.Query("user u").Select("u.id", "count(*)")
+	sb.JoinWithOption(builder.LeftJoin, "profile p", "p.user_id = u.id")
+	sb.Where(sb.Cond.In("u.id", sub), sb.Cond.Or(sb.Cond.EQ("a", 1), sb.Cond.EQ("b", 2)))
+	sb.GroupBy("u.id").OrderBy("u.id").Limit(10)
+
+	s, err := builder.Inline(sb, driver.PostgreSQL)
+	tt.NoError(err)
+	tt.Equal(`SELECT "u"."id", count(*)
+FROM "user" u
+LEFT JOIN profile p ON p.user_id = u.id
+WHERE "u"."id" IN (
+  SELECT "user_id"
+  FROM "order"
+  WHERE "amount" > 100
+    AND "day" BETWEEN 1 AND 7
+)
+  AND ("a" = 1 OR "b" = 2)
+GROUP BY u.id
+ORDER BY u.id
+LIMIT 10`, builder.Pretty(s))
+}
+
+func TestStringNested(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sub := builder.Query("b").Select("id")
+	sub.Where(sub.Cond.EQ("x", 1))
+	sb := builder.Query("a")
+	sb.Where(sb.Cond.In("id", sub), sb.Cond.EQ("c", builder.Raw("NOW()")), sb.Cond.JSONEQ("meta", "a", "v"))
+	tt.Equal(`SELECT * FROM "a" WHERE "id" IN (SELECT "id" FROM "b" WHERE "x" = 1) AND "c" = NOW() AND json_extract("meta", $.a) = v`, sb.String())
+}
+
+type encodedName string
+
+func TestEncoder(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	encode := func(v interface{}) (interface{}, error) {
+		if n, ok := v.(encodedName); ok {
+			if n == "" {
+				return nil, errors.New("empty name")
+			}
+			return "name:" + string(n), nil
+		}
+		return v, nil
+	}
+
+	sub := builder.Query("b").Select("id")
+	sub.Where(sub.Cond.EQ("name", encodedName("b")))
+	sb := builder.Query("a").SetEncoder(encode)
+	sb.Where(sb.Cond.EQ("name", encodedName("a")), sb.Cond.In("id", sub))
+
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "a" WHERE "name" = ? AND "id" IN (SELECT "id" FROM "b" WHERE "name" = ?)`, sql)
+	tt.Equal([]interface{}{"name:a", "name:b"}, values)
+
+	s, err := builder.Inline(sb, driver.MySQL)
+	tt.NoError(err)
+	tt.Equal("SELECT * FROM `a` WHERE `name` = 'name:a' AND `id` IN (SELECT `id` FROM `b` WHERE `name` = 'name:b')", s)
+	tt.Equal(`SELECT * FROM "a" WHERE "name" = name:a AND "id" IN (SELECT "id" FROM "b" WHERE "name" = name:b)`, sb.String())
+
+	ub := builder.Update("a").SetEncoder(encode)
+	ub.Set(ub.Assign("name", encodedName(""))).Where(ub.Cond.EQ("id", 1))
+	_, _, err = ub.Build()
+	tt.EqualTrue(err != nil)
+}
@@ -0,0 +1,63 @@
+package builder
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Scope is a reusable part of a query, such as a common filter
+type Scope func(b *SelectBuilder)
+
+var scopes = struct {
+	m  map[string]Scope
+	mu sync.RWMutex
+}{m: map[string]Scope{}}
+
+// RegisterScope registers scope by name so that any query can apply it with UseScope,
+// registering a name again replaces the scope
+func RegisterScope(name string, scope Scope) {
+	scopes.mu.Lock()
+	scopes.m[name] = scope
+	scopes.mu.Unlock()
+}
+
+// LookupScope returns the scope registered by name
+func LookupScope(name string) (Scope, bool) {
+	scopes.mu.RLock()
+	scope, ok := scopes.m[name]
+	scopes.mu.RUnlock()
+	return scope, ok
+}
+
+// Scopes combines scopes into one applied in order
+func Scopes(scope ...Scope) Scope {
+	return func(b *SelectBuilder) {
+		b.Apply(scope...)
+	}
+}
+
+// Apply applies scopes to the SELECT in order
+func (b *SelectBuilder) Apply(scope ...Scope) *SelectBuilder {
+	for _, fn := range scope {
+		if fn != nil {
+			fn(b)
+		}
+	}
+	return b
+}
+
+// UseScope applies the registered scopes to the SELECT in order,
+// an unknown name is returned as an error by Build
+func (b *SelectBuilder) UseScope(name ...string) *SelectBuilder {
+	for _, n := range name {
+		scope, ok := LookupScope(n)
+		if !ok {
+			if b.err == nil {
+				b.err = fmt.Errorf("select error: scope %s is not registered", n)
+			}
+			continue
+		}
+		scope(b)
+	}
+	return b
+}
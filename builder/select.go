@@ -15,21 +15,27 @@ type (
 	// SelectBuilder is a builder to build SELECT
 	SelectBuilder struct {
 		Cond        *BuildCond
+		err         error
 		order       string
-		forWhat     string
 		havingExprs []string
 		joinOptions []JoinOption
 		joinTables  []string
 		joinExprs   [][]string
+		joinUsing   [][]string
+		joinLateral []bool
+		windows     []namedWindow
 		whereExprs  []string
 		groupByCols []string
 		orderByCols []string
 		selectCols  []string
 		tables      []string
+		lock        rowLock
+		with        withClause
 		limit       int
 		offset      int
 		done        bool
 		distinct    bool
+		windowed    bool
 	}
 )
 
@@ -85,6 +91,18 @@ func (b *SelectBuilder) SetDriver(driver driver.Dialect) *SelectBuilder {
 	return b
 }
 
+// With adds a common table expression before the SELECT, cols optionally names its columns
+func (b *SelectBuilder) With(name string, builder Builder, cols ...string) *SelectBuilder {
+	b.with.add(false, name, builder, cols)
+	return b
+}
+
+// WithRecursive adds a recursive common table expression before the SELECT
+func (b *SelectBuilder) WithRecursive(name string, builder Builder, cols ...string) *SelectBuilder {
+	b.with.add(true, name, builder, cols)
+	return b
+}
+
 // Distinct marks this SELECT as DISTINCT
 func (b *SelectBuilder) Distinct() *SelectBuilder {
 	b.distinct = true
@@ -130,6 +148,8 @@ func (b *SelectBuilder) JoinWithOption(option JoinOption, table string, onExpr .
 	b.joinOptions = append(b.joinOptions, option)
 	b.joinTables = append(b.joinTables, table)
 	b.joinExprs = append(b.joinExprs, onExpr)
+	b.joinUsing = append(b.joinUsing, nil)
+	b.joinLateral = append(b.joinLateral, option == CrossApply || option == OuterApply)
 	return b
 }
 
@@ -139,6 +159,17 @@ func (b *SelectBuilder) Where(expr ...string) *SelectBuilder {
 	return b
 }
 
+// WhereBy adds the conditions of a map or a filter struct to WHERE, see BuildCond.Parse
+func (b *SelectBuilder) WhereBy(cond interface{}) *SelectBuilder {
+	expr, err := b.Cond.Parse(cond)
+	if err != nil {
+		b.err = err
+	} else if expr != "" {
+		b.whereExprs = append(b.whereExprs, expr)
+	}
+	return b
+}
+
 // Having sets expressions of HAVING in SELECT
 func (b *SelectBuilder) Having(expr ...string) *SelectBuilder {
 	b.havingExprs = append(b.havingExprs, expr...)
@@ -185,21 +216,28 @@ func (b *SelectBuilder) Limit(limit int) *SelectBuilder {
 	return b
 }
 
+// HasLimit reports whether LIMIT is set in SELECT
+func (b *SelectBuilder) HasLimit() bool {
+	return b.limit > 0
+}
+
 // Offset sets the LIMIT offset in SELECT
 func (b *SelectBuilder) Offset(offset int) *SelectBuilder {
 	b.offset = offset
 	return b
 }
 
-// ForUpdate adds FOR UPDATE at the end of SELECT statement
+// ForUpdate adds FOR UPDATE at the end of SELECT statement,
+// MSSQL uses the UPDLOCK table hint and SQLite ignores it
 func (b *SelectBuilder) ForUpdate() *SelectBuilder {
-	b.forWhat = "UPDATE"
+	b.lock.mode = "UPDATE"
 	return b
 }
 
-// ForShare adds FOR SHARE at the end of SELECT statement
+// ForShare adds FOR SHARE at the end of SELECT statement,
+// MSSQL uses the HOLDLOCK table hint and SQLite ignores it
 func (b *SelectBuilder) ForShare() *SelectBuilder {
-	b.forWhat = "SHARE"
+	b.lock.mode = "SHARE"
 	return b
 }
 
@@ -224,11 +262,31 @@ func (b *SelectBuilder) String() string {
 
 // Build returns compiled SELECT string and Cond
 func (b *SelectBuilder) Build() (sql string, values []interface{}, err error) {
+	if err = b.check(); err != nil {
+		return "", nil, err
+	}
 	sql, values = b.build(false)
+	if b.Cond.err != nil {
+		return "", nil, b.Cond.err
+	}
 	return
 }
 
-func (b *SelectBuilder) build(blend bool) (sql string, values []interface{}) {
+// check returns the errors found while the SELECT was assembled
+func (b *SelectBuilder) check() error {
+	if b.err != nil {
+		return b.err
+	}
+	if err := b.checkLock(); err != nil {
+		return err
+	}
+	if err := b.checkJoins(); err != nil {
+		return err
+	}
+	return b.checkWindows()
+}
+
+func (b *SelectBuilder) build(blend bool, initial ...interface{}) (sql string, values []interface{}) {
 	// More accurate size estimation
 	estimatedSize := 64 // Base size for "SELECT" + "FROM" + spaces
 
@@ -300,10 +358,12 @@ func (b *SelectBuilder) build(blend bool) (sql string, values []interface{}) {
 		estimatedSize += 20 // " OFFSET " + number
 	}
 
-	if b.forWhat != "" {
-		estimatedSize += len(b.forWhat) + 5 // " FOR " + forWhat
+	if b.lock.mode != "" {
+		estimatedSize += 32 // " FOR " + mode + options
 	}
 
+	prefix, initial := b.with.build(b.Cond, blend, initial)
+
 	buf := zutil.GetBuff(uint(estimatedSize))
 	defer zutil.PutBuff(buf)
 	buf.WriteString("SELECT ")
@@ -315,8 +375,7 @@ func (b *SelectBuilder) build(blend bool) (sql string, values []interface{}) {
 	if len(b.selectCols) == 0 {
 		buf.WriteString("*")
 	} else {
-		driverValue := b.Cond.driver.Value()
-		quotedCols := driverValue.QuoteCols(b.selectCols)
+		quotedCols := b.Cond.quoteSelectCols(b.selectCols)
 
 		for i, col := range quotedCols {
 			if i > 0 {
@@ -329,33 +388,17 @@ func (b *SelectBuilder) build(blend bool) (sql string, values []interface{}) {
 	buf.WriteString(" FROM ")
 
 	driverValue := b.Cond.driver.Value()
-	quotedTables := driverValue.QuoteCols(b.tables)
+	quotedTables := b.Cond.quoteCols(b.tables)
 	for i, table := range quotedTables {
 		if i > 0 {
 			buf.WriteString(", ")
 		}
 		buf.WriteString(table)
+		buf.WriteString(b.lockHint(driverValue, table))
 	}
 
 	for i := range b.joinTables {
-		if option := b.joinOptions[i]; option != "" {
-			buf.WriteRune(' ')
-			buf.WriteString(string(option))
-		}
-
-		buf.WriteString(" JOIN ")
-		buf.WriteString(b.joinTables[i])
-
-		if exprs := b.joinExprs[i]; len(exprs) > 0 {
-			buf.WriteString(" ON ")
-
-			for j, expr := range exprs {
-				if j > 0 {
-					buf.WriteString(" AND ")
-				}
-				buf.WriteString(expr)
-			}
-		}
+		b.buildJoin(buf, driverValue, i)
 	}
 
 	if len(b.whereExprs) > 0 {
@@ -376,7 +419,7 @@ func (b *SelectBuilder) build(blend bool) (sql string, values []interface{}) {
 			if i > 0 {
 				buf.WriteString(", ")
 			}
-			buf.WriteString(col)
+			buf.WriteString(b.Cond.quoteOrder(col))
 		}
 
 		if len(b.havingExprs) > 0 {
@@ -391,6 +434,8 @@ func (b *SelectBuilder) build(blend bool) (sql string, values []interface{}) {
 		}
 	}
 
+	buf.WriteString(b.buildWindows())
+
 	if len(b.orderByCols) > 0 {
 		buf.WriteString(" ORDER BY ")
 
@@ -398,7 +443,7 @@ func (b *SelectBuilder) build(blend bool) (sql string, values []interface{}) {
 			if i > 0 {
 				buf.WriteString(", ")
 			}
-			buf.WriteString(col)
+			buf.WriteString(b.Cond.quoteOrder(col))
 		}
 
 		if b.order != "" {
@@ -451,16 +496,14 @@ func (b *SelectBuilder) build(blend bool) (sql string, values []interface{}) {
 		}
 	}
 
-	if b.forWhat != "" {
-		buf.WriteString(" FOR ")
-		buf.WriteString(b.forWhat)
-	}
+	buf.WriteString(b.buildLock(driverValue))
 
 	if blend {
-		return b.Cond.CompileString(buf.String()), nil
+		return prefix + b.Cond.CompileString(buf.String(), initial...), nil
 	}
 
-	return b.Cond.Compile(buf.String())
+	sql, values = b.Cond.Compile(buf.String(), initial...)
+	return prefix + sql, values
 }
 
 // Safety performs safety checks on the SELECT builder
@@ -536,3 +579,15 @@ func splitSelectColumns(input string) []string {
 
 	return cols
 }
+
+// nestedBuild compiles a copy of b as a part of parent, so that a subquery
+// shared by several statements can be compiled concurrently
+func (b *SelectBuilder) nestedBuild(parent *BuildCond, blend bool, initial []interface{}) (string, []interface{}, error) {
+	c := b.Clone()
+	c.Cond.inherit(parent)
+	if err := c.check(); err != nil {
+		return "", initial, err
+	}
+	sql, values := c.build(blend, initial...)
+	return sql, values, c.Cond.err
+}
@@ -4,6 +4,7 @@ import (
 	dbsql "database/sql"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/sohaha/zlsgo"
 	"github.com/zlsgo/zdb/builder"
@@ -206,6 +207,61 @@ func TestSelectForShare(t *testing.T) {
 	tt.EqualTrue(strings.Contains(sql, "FOR SHARE"))
 }
 
+func TestSelectLock(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	build := func(sb *builder.SelectBuilder) string {
+		sql, _, err := sb.Build()
+		tt.NoError(err)
+		return sql
+	}
+
+	sb := builder.Query("job j").Join("worker w", "w.id = j.worker_id").SetDriver(&mysql.Config{})
+	tt.Equal("SELECT * FROM `job` j JOIN worker w ON w.id = j.worker_id FOR UPDATE OF `j` SKIP LOCKED", build(sb.ForUpdate().Of("j").SkipLocked()))
+
+	sb = builder.Query("job").SetDriver(&postgres.Config{}).Limit(1)
+	tt.Equal(`SELECT * FROM "job" LIMIT 1 FOR SHARE NOWAIT`, build(sb.ForShare().NoWait()))
+
+	sb = builder.Query("job j").Join("worker w", "w.id = j.worker_id").SetDriver(&mssql.Config{})
+	sb.ForUpdate().SkipLocked()
+	tt.Equal(`SELECT * FROM "job" j WITH (UPDLOCK, READPAST) JOIN worker w WITH (UPDLOCK, READPAST) ON w.id = j.worker_id`, build(sb))
+	tt.Equal(`SELECT * FROM "job" j WITH (UPDLOCK, READPAST) JOIN worker w ON w.id = j.worker_id`, build(sb.Of("job")))
+
+	sb = builder.Query("job").SetDriver(&mssql.Config{}).ForShare().NoWait()
+	tt.Equal(`SELECT * FROM "job" WITH (HOLDLOCK, NOWAIT)`, build(sb))
+
+	sb = builder.Query("job").SetDriver(&sqlite3.Config{}).ForUpdate().SkipLocked().Of("job")
+	tt.Equal(`SELECT * FROM "job"`, build(sb))
+
+	sb = builder.Query("job").SetDriver(&mysql.Config{Version: "5.7.44"}).ForShare()
+	tt.Equal("SELECT * FROM `job` LOCK IN SHARE MODE", build(sb))
+	_, _, err := sb.NoWait().Build()
+	tt.EqualTrue(err != nil)
+}
+
+func TestSelectLockTimeout(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	set, reset := builder.Query("job").SetDriver(&mysql.Config{}).LockTimeout(1500 * time.Millisecond).LockTimeoutStatements()
+	tt.Equal("", set)
+	tt.Equal("", reset)
+
+	set, reset = builder.Query("job").SetDriver(&mysql.Config{}).ForUpdate().LockTimeout(1500 * time.Millisecond).LockTimeoutStatements()
+	tt.Equal("SET @zdb_lock_wait_timeout = @@SESSION.innodb_lock_wait_timeout, SESSION innodb_lock_wait_timeout = 2", set)
+	tt.Equal("SET SESSION innodb_lock_wait_timeout = @zdb_lock_wait_timeout", reset)
+
+	set, reset = builder.Query("job").SetDriver(&postgres.Config{}).ForUpdate().LockTimeout(time.Second).LockTimeoutStatements()
+	tt.Equal("SET LOCAL lock_timeout = '1000ms'", set)
+	tt.Equal("", reset)
+
+	set, reset = builder.Query("job").SetDriver(&mssql.Config{}).ForUpdate().LockTimeout(time.Second).LockTimeoutStatements()
+	tt.Equal("SET LOCK_TIMEOUT 1000", set)
+	tt.Equal("SET LOCK_TIMEOUT -1", reset)
+
+	set, _ = builder.Query("job").SetDriver(&sqlite3.Config{}).ForUpdate().LockTimeout(time.Second).LockTimeoutStatements()
+	tt.Equal("", set)
+}
+
 func TestSelectSafety(t *testing.T) {
 	tt := zlsgo.NewTest(t)
 
@@ -0,0 +1,133 @@
+package builder
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/zlsgo/zdb/driver"
+)
+
+type (
+	sourceJoin struct {
+		option JoinOption
+		table  string
+		on     []string
+	}
+	// sources holds the tables an UPDATE or DELETE reads besides its target,
+	// tables are listed after FROM or USING and joins are written per dialect
+	sources struct {
+		joins  []sourceJoin
+		tables []string
+	}
+)
+
+func (s *sources) join(option JoinOption, table string, on []string) {
+	s.joins = append(s.joins, sourceJoin{option: option, table: table, on: on})
+}
+
+func (s *sources) empty() bool {
+	return len(s.joins) == 0 && len(s.tables) == 0
+}
+
+// check rejects the sources the dialect cannot express, kind names the statement in errors
+func (s *sources) check(kind string, d driver.Typ, limit int) error {
+	if s.empty() {
+		return nil
+	}
+	if limit >= 0 {
+		return errors.New(kind + " error: limit cannot be combined with joined tables")
+	}
+	switch d {
+	case driver.MySQL, driver.MsSQL:
+		return nil
+	case driver.PostgreSQL, driver.SQLite, driver.Doris:
+		for _, j := range s.joins {
+			if j.option != "" && j.option != InnerJoin {
+				return errors.New(kind + " error: " + string(j.option) + " JOIN is not supported by " + d.String())
+			}
+		}
+		return nil
+	}
+	return errors.New(kind + " error: joined tables are not supported by " + d.String())
+}
+
+// quoteTables quotes the tables listed after FROM or USING
+func (s *sources) quoteTables(c *BuildCond) []string {
+	return c.quoteCols(EscapeAll(s.tables...))
+}
+
+// joinTable returns the table of a JOIN, it is only quoted in strict mode
+// since it may be a subquery otherwise
+func (s *sources) joinTable(c *BuildCond, j sourceJoin) string {
+	if c.strict {
+		return c.quote(j.table)
+	}
+	return j.table
+}
+
+// write appends ", tables" and the JOIN clauses to buf
+func (s *sources) write(buf *bytes.Buffer, c *BuildCond) {
+	if len(s.tables) > 0 {
+		buf.WriteString(", ")
+		buf.WriteString(strings.Join(s.quoteTables(c), ", "))
+	}
+	for _, j := range s.joins {
+		if j.option != "" {
+			buf.WriteRune(' ')
+			buf.WriteString(string(j.option))
+		}
+		buf.WriteString(" JOIN ")
+		buf.WriteString(s.joinTable(c, j))
+		if len(j.on) > 0 {
+			buf.WriteString(" ON ")
+			buf.WriteString(strings.Join(j.on, " AND "))
+		}
+	}
+}
+
+// flatten lists the joined tables as plain sources and returns their ON
+// expressions as conditions, for the dialects that use FROM or USING
+func (s *sources) flatten(c *BuildCond) (tables []string, conds []string) {
+	tables = s.quoteTables(c)
+	for _, j := range s.joins {
+		tables = append(tables, s.joinTable(c, j))
+		conds = append(conds, j.on...)
+	}
+	return
+}
+
+// aliasTable returns the quoted table with its alias after AS, PostgreSQL and SQLite
+// only accept the alias of the target of UPDATE and DELETE after AS
+func aliasTable(c *BuildCond, table string) string {
+	fields := strings.Fields(table)
+	if len(fields) < 2 {
+		return c.quote(table)
+	}
+	return c.quote(fields[0]) + " AS " + targetName(c, table)
+}
+
+// unqualify removes the "target." prefix from the column of an assignment,
+// the columns in SET of UPDATE ... FROM cannot be qualified
+func unqualify(assignment, target string) string {
+	target = strings.Trim(target, "`\"")
+	for _, prefix := range []string{target + ".", `"` + target + `".`, "`" + target + "`."} {
+		if strings.HasPrefix(assignment, prefix) {
+			return assignment[len(prefix):]
+		}
+	}
+	return assignment
+}
+
+// targetName returns the alias of table, or the quoted table without alias,
+// the alias is quoted in strict mode like the alias of the table
+func targetName(c *BuildCond, table string) string {
+	fields := strings.Fields(table)
+	if len(fields) > 1 {
+		if c.strict {
+			return c.quote(fields[len(fields)-1])
+		}
+		return fields[len(fields)-1]
+	}
+	return c.quote(table)
+}
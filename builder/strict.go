@@ -0,0 +1,231 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zlsgo/zdb/driver"
+)
+
+// SetStrict validates the identifiers of the SELECT, see BuildCond.SetStrict
+func (b *SelectBuilder) SetStrict(strict bool) *SelectBuilder {
+	b.Cond.SetStrict(strict)
+	return b
+}
+
+// SetStrict validates the identifiers of the INSERT, see BuildCond.SetStrict
+func (b *InsertBuilder) SetStrict(strict bool) *InsertBuilder {
+	b.cond.SetStrict(strict)
+	return b
+}
+
+// SetStrict validates the identifiers of the UPDATE, see BuildCond.SetStrict
+func (b *UpdateBuilder) SetStrict(strict bool) *UpdateBuilder {
+	b.Cond.SetStrict(strict)
+	return b
+}
+
+// SetStrict validates the identifiers of the DELETE, see BuildCond.SetStrict
+func (b *DeleteBuilder) SetStrict(strict bool) *DeleteBuilder {
+	b.Cond.SetStrict(strict)
+	return b
+}
+
+// SetStrict makes tables, columns, aliases and ORDER BY columns accept identifiers only,
+// such as "name", "u.name", "user u", "name AS n" or "name DESC". Quotes inside a name are
+// escaped for the dialect, expressions must be passed as placeholders of Var(Raw(expr)).
+// An invalid identifier is written as one quoted name and returned as an error by Build
+func (c *BuildCond) SetStrict(strict bool) {
+	c.strict = strict
+}
+
+// quote quotes an identifier with an optional alias, placeholders are kept as they are
+func (c *BuildCond) quote(col string) string {
+	return c.quoteIdent(col, false)
+}
+
+// quoteCols quotes a list of identifiers with optional aliases
+func (c *BuildCond) quoteCols(cols []string) []string {
+	if len(cols) == 0 {
+		return cols
+	}
+	quoted := make([]string, len(cols))
+	for i := range cols {
+		quoted[i] = c.quote(cols[i])
+	}
+	return quoted
+}
+
+// quoteSelectCols quotes the columns of SELECT, outside strict mode a bare integer
+// such as the 1 of EXISTS (SELECT 1 ...) is a literal and kept as it is
+func (c *BuildCond) quoteSelectCols(cols []string) []string {
+	quoted := make([]string, len(cols))
+	for i := range cols {
+		if !c.strict && isInteger(cols[i]) {
+			quoted[i] = cols[i]
+		} else {
+			quoted[i] = c.quote(cols[i])
+		}
+	}
+	return quoted
+}
+
+// quoteOrderCols quotes the columns of ORDER BY of UPDATE and DELETE
+func (c *BuildCond) quoteOrderCols(cols []string) []string {
+	quoted := make([]string, len(cols))
+	for i := range cols {
+		if c.strict {
+			quoted[i] = c.quoteIdent(cols[i], true)
+		} else {
+			quoted[i] = c.driver.Value().Quote(cols[i])
+		}
+	}
+	return quoted
+}
+
+// quoteOrder quotes a column of ORDER BY with an optional direction, it is only
+// quoted in strict mode since ORDER BY of SELECT accepts expressions otherwise
+func (c *BuildCond) quoteOrder(col string) string {
+	if !c.strict {
+		return col
+	}
+	return c.quoteIdent(col, true)
+}
+
+func (c *BuildCond) quoteIdent(col string, order bool) string {
+	d := c.driver.Value()
+	if !c.strict {
+		if isPlaceholder(strings.Fields(col)) {
+			return col
+		}
+		return d.Quote(col)
+	}
+
+	s, err := strictIdent(d, col, order)
+	if err != nil {
+		if c.err == nil {
+			c.err = err
+		}
+		return quoteName(d, col)
+	}
+	return s
+}
+
+// strictIdent validates and quotes "name", "name alias" or "name AS alias",
+// with order the second word is the direction instead of an alias
+func strictIdent(d driver.Typ, col string, order bool) (string, error) {
+	fields := strings.Fields(col)
+	invalid := fmt.Errorf("strict error: invalid identifier %q, wrap expressions in builder.Raw", col)
+
+	var name, alias, sep string
+	switch {
+	case len(fields) == 1:
+		name = fields[0]
+	case len(fields) == 2 && order:
+		dir := strings.ToUpper(fields[1])
+		if dir != "ASC" && dir != "DESC" {
+			return "", invalid
+		}
+		name, sep = fields[0], " "+dir
+	case len(fields) == 2:
+		name, alias, sep = fields[0], fields[1], " "
+	case len(fields) == 3 && !order && strings.EqualFold(fields[1], "AS"):
+		name, alias, sep = fields[0], fields[2], " AS "
+	default:
+		return "", invalid
+	}
+
+	if name == "*" {
+		return name, nil
+	}
+	if !isPlaceholder([]string{name}) {
+		if strings.HasPrefix(name, "(") {
+			if !isPlaceholder([]string{strings.TrimSuffix(strings.TrimPrefix(name, "("), ")")}) {
+				return "", invalid
+			}
+		} else {
+			parts := strings.Split(name, ".")
+			for i, part := range parts {
+				if part == "*" && i == len(parts)-1 && i > 0 {
+					continue
+				}
+				q, ok := strictPart(d, part)
+				if !ok {
+					return "", invalid
+				}
+				parts[i] = q
+			}
+			name = strings.Join(parts, ".")
+		}
+	}
+
+	if alias == "" {
+		return name + sep, nil
+	}
+	q, ok := strictPart(d, alias)
+	if !ok {
+		return "", invalid
+	}
+	return name + sep + q, nil
+}
+
+// strictPart quotes one part of a name, a part already quoted for the dialect is kept
+func strictPart(d driver.Typ, part string) (string, bool) {
+	q := identQuote(d)
+	if part == "" {
+		return "", false
+	}
+	if q != 0 && len(part) > 1 && part[0] == q && part[len(part)-1] == q {
+		inner := part[1 : len(part)-1]
+		if inner == "" || strings.IndexByte(strings.ReplaceAll(inner, string([]byte{q, q}), ""), q) >= 0 {
+			return "", false
+		}
+		return part, true
+	}
+	for i := 0; i < len(part); i++ {
+		switch ch := part[i]; {
+		case ch < ' ' || ch == 0x7f:
+			return "", false
+		case strings.IndexByte(";(),'\\[]", ch) >= 0:
+			return "", false
+		}
+	}
+	if strings.Contains(part, "--") || strings.Contains(part, "/*") {
+		return "", false
+	}
+	return quoteName(d, part), true
+}
+
+// quoteName quotes name as a single identifier, quotes inside it are doubled
+func quoteName(d driver.Typ, name string) string {
+	q := identQuote(d)
+	if q == 0 {
+		return name
+	}
+	return string(q) + strings.ReplaceAll(name, string(q), string([]byte{q, q})) + string(q)
+}
+
+// isPlaceholder reports whether fields start with a placeholder of Var
+func isInteger(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isPlaceholder(fields []string) bool {
+	if len(fields) == 0 || len(fields[0]) < 2 || fields[0][0] != '$' {
+		return false
+	}
+	for _, ch := range fields[0][1:] {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver/mysql"
+	"github.com/zlsgo/zdb/driver/postgres"
+)
+
+func TestStrict(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sb := builder.Query("user u").SetStrict(true)
+	sb.Select("u.id", `na"me AS n`, "u.*").OrderBy("id DESC")
+	sb.Where(sb.Cond.EQ("status", 1))
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT "u"."id", "na""me" AS "n", "u".* FROM "user" "u" WHERE "status" = ? ORDER BY "id" DESC`, sql)
+	tt.Equal([]interface{}{1}, values)
+
+	sb = builder.Query("user").SetDriver(&mysql.Config{}).SetStrict(true)
+	sb.Select("na`me")
+	sql, _, err = sb.Build()
+	tt.NoError(err)
+	tt.Equal("SELECT `na``me` FROM `user`", sql)
+
+	sb = builder.Query("user").SetStrict(true)
+	sb.Select("count(*)")
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+
+	sb = builder.Query("user").SetStrict(true)
+	sb.Select(sb.As(sb.Cond.Var(builder.Raw("count(*)")), "total"))
+	sql, _, err = sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT count(*) AS "total" FROM "user"`, sql)
+
+	sb = builder.Query("user").SetStrict(true)
+	sb.OrderBy("id; DROP TABLE user")
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+
+	sb = builder.Query("user")
+	sb.Select("count(*)").OrderBy("id DESC")
+	sql, _, err = sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT count(*) FROM "user" ORDER BY id DESC`, sql)
+
+	ib := builder.Insert("user").SetStrict(true).Cols(`a"b`).Values(1)
+	sql, _, err = ib.Build()
+	tt.NoError(err)
+	tt.Equal(`INSERT INTO "user" ("a""b") VALUES (?)`, sql)
+
+	ub := builder.Update("user; --").SetStrict(true)
+	ub.Set(ub.Assign("name", "a"))
+	_, _, err = ub.Build()
+	tt.EqualTrue(err != nil)
+
+	db := builder.Delete("user").SetStrict(true)
+	db.Where(db.Cond.EQ("id", 1))
+	sql, _, err = db.Build()
+	tt.NoError(err)
+	tt.Equal(`DELETE FROM "user" WHERE "id" = ?`, sql)
+}
+
+func TestStrictIdentifiers(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+	bad := "a` OR 1=1 -- "
+
+	sb := builder.Query("user").SetStrict(true)
+	sb.Where(sb.Cond.JSONEQ("meta", "$.name", "a"))
+	sql, _, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" WHERE json_extract("meta", ?) = ?`, sql)
+
+	sb = builder.Query("user").SetStrict(true)
+	sb.Where(sb.Cond.JSONEQ(bad, "$.name", "a"))
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+
+	sb = builder.Query("user").SetStrict(true)
+	sb.Where(sb.Cond.EQ("a", builder.Column("u.b")))
+	sql, _, err = sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" WHERE "a" = "u"."b"`, sql)
+
+	sb = builder.Query("user").SetStrict(true)
+	sb.Where(sb.Cond.EQ("a", builder.Column(bad)))
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+
+	ub := builder.Update("user u").SetStrict(true).Join("team t", "t.id = u.team_id")
+	ub.Set(ub.Assign("name", "a")).Where(ub.Cond.EQ("u.id", 1))
+	_, _, err = ub.Build()
+	tt.NoError(err)
+
+	ub = builder.Update("user").SetStrict(true).Join("(select 1) x", "x.id = user.id")
+	ub.Set(ub.Assign("name", "a")).Where(ub.Cond.EQ("id", 1))
+	_, _, err = ub.Build()
+	tt.EqualTrue(err != nil)
+
+	ub = builder.Update("user").SetStrict(true).From(bad)
+	ub.Set(ub.Assign("name", "a")).Where(ub.Cond.EQ("id", 1))
+	_, _, err = ub.Build()
+	tt.EqualTrue(err != nil)
+
+	db := builder.Delete("user").SetStrict(true).Join("(select 1) x", "x.id = user.id")
+	db.Where(db.Cond.EQ("id", 1))
+	_, _, err = db.Build()
+	tt.EqualTrue(err != nil)
+
+	sb = builder.Query("user").SetStrict(true)
+	sb.Select(sb.As(sb.Cond.Var(builder.Raw(sb.RowNumber(builder.Window().PartitionBy("team").OrderBy("id DESC")))), "n"))
+	sql, _, err = sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT ROW_NUMBER() OVER (PARTITION BY "team" ORDER BY "id" DESC) AS "n" FROM "user"`, sql)
+
+	sb = builder.Query("user").SetStrict(true)
+	sb.Select(sb.As(sb.Cond.Var(builder.Raw(sb.RowNumber(builder.Window().PartitionBy(bad)))), "n"))
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+
+	sb = builder.Query("user").SetStrict(true)
+	sb.Select(sb.As(sb.Cond.Var(builder.Raw(sb.RowNumber(builder.Window().OrderBy("id; DROP TABLE user")))), "n"))
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+
+	sb = builder.Query("user").SetStrict(true)
+	sb.Select(sb.As(sb.Cond.Var(builder.Raw(sb.RowNumber(builder.Window(bad)))), "n"))
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+
+	sb = builder.Query("user").SetStrict(true)
+	sb.Window(bad, builder.Window().OrderBy("id"))
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+
+	sb = builder.Query("t").SetStrict(true)
+	sb.With(bad, builder.Query("user"))
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+
+	sb = builder.Query("t").SetStrict(true)
+	sb.With("t", builder.Query("user"), bad)
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+
+	sb = builder.Query("user u").SetDriver(&postgres.Config{}).SetStrict(true)
+	sb.ForUpdate().Of("u")
+	sql, _, err = sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" "u" FOR UPDATE OF "u"`, sql)
+
+	sb = builder.Query("user").SetDriver(&postgres.Config{}).SetStrict(true)
+	sb.ForUpdate().Of(bad)
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+}
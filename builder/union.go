@@ -4,22 +4,32 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/sohaha/zlsgo/zutil"
 	"github.com/zlsgo/zdb/driver"
 )
 
-// UnionBuilder is a builder to build UNION
+// UnionBuilder is a builder to build UNION, INTERSECT and EXCEPT
 type UnionBuilder struct {
 	cond        *BuildCond
 	opt         string
 	order       string
 	builders    []*SelectBuilder
+	sets        []setOperand
 	orderByCols []string
 	limit       int
 	offset      int
 }
 
+// setOperand is a query combined with the result before it by a set operator
+type setOperand struct {
+	builder Builder
+	opt     string
+}
+
+var errSetUnsupported = errors.New("union error: INTERSECT and EXCEPT require MySQL 8.0.31")
+
 var _ Builder = new(UnionBuilder)
 
 // Union creates a new UNION builder
@@ -46,6 +56,67 @@ func UnionAll(builders ...*SelectBuilder) *UnionBuilder {
 	}
 }
 
+// Intersect creates a new set builder using INTERSECT operator
+func Intersect(builders ...*SelectBuilder) *UnionBuilder {
+	return setBuilder(" INTERSECT ", builders)
+}
+
+// IntersectAll creates a new set builder using INTERSECT ALL operator
+func IntersectAll(builders ...*SelectBuilder) *UnionBuilder {
+	return setBuilder(" INTERSECT ALL ", builders)
+}
+
+// Except creates a new set builder using EXCEPT operator
+func Except(builders ...*SelectBuilder) *UnionBuilder {
+	return setBuilder(" EXCEPT ", builders)
+}
+
+// ExceptAll creates a new set builder using EXCEPT ALL operator
+func ExceptAll(builders ...*SelectBuilder) *UnionBuilder {
+	return setBuilder(" EXCEPT ALL ", builders)
+}
+
+func setBuilder(opt string, builders []*SelectBuilder) *UnionBuilder {
+	b := Union(builders...)
+	b.opt = opt
+	return b
+}
+
+// Union combines the result so far with builder by UNION, the result so far is evaluated first
+func (b *UnionBuilder) Union(builder Builder) *UnionBuilder {
+	return b.combine(" UNION ", builder)
+}
+
+// UnionAll combines the result so far with builder by UNION ALL
+func (b *UnionBuilder) UnionAll(builder Builder) *UnionBuilder {
+	return b.combine(" UNION ALL ", builder)
+}
+
+// Intersect combines the result so far with builder by INTERSECT
+func (b *UnionBuilder) Intersect(builder Builder) *UnionBuilder {
+	return b.combine(" INTERSECT ", builder)
+}
+
+// IntersectAll combines the result so far with builder by INTERSECT ALL
+func (b *UnionBuilder) IntersectAll(builder Builder) *UnionBuilder {
+	return b.combine(" INTERSECT ALL ", builder)
+}
+
+// Except combines the result so far with builder by EXCEPT
+func (b *UnionBuilder) Except(builder Builder) *UnionBuilder {
+	return b.combine(" EXCEPT ", builder)
+}
+
+// ExceptAll combines the result so far with builder by EXCEPT ALL
+func (b *UnionBuilder) ExceptAll(builder Builder) *UnionBuilder {
+	return b.combine(" EXCEPT ALL ", builder)
+}
+
+func (b *UnionBuilder) combine(opt string, builder Builder) *UnionBuilder {
+	b.sets = append(b.sets, setOperand{opt: opt, builder: builder})
+	return b
+}
+
 // SetDriver Set the compilation statements driver
 func (b *UnionBuilder) SetDriver(driver driver.Dialect) *UnionBuilder {
 	b.cond.driver = driver
@@ -90,11 +161,54 @@ func (b *UnionBuilder) String() string {
 
 // Build returns compiled SELECT string and Cond
 func (b *UnionBuilder) Build() (sql string, values []interface{}, err error) {
+	if err = b.check(); err != nil {
+		return "", nil, err
+	}
 	sql, values = b.build(false)
+	if b.cond.err != nil {
+		return "", nil, b.cond.err
+	}
 	return
 }
 
-func (b *UnionBuilder) build(blend bool) (sql string, args []interface{}) {
+// check rejects the set operators the dialect does not support
+func (b *UnionBuilder) check() error {
+	d := b.cond.driver.Value()
+	opts := make([]string, 0, len(b.sets)+1)
+	opts = append(opts, b.opt)
+	for _, s := range b.sets {
+		opts = append(opts, s.opt)
+	}
+	for _, opt := range opts {
+		if opt == " UNION " || opt == " UNION ALL " {
+			continue
+		}
+		if d == driver.MySQL && driver.VersionBelow(b.cond.driver, "8.0.31") {
+			return errSetUnsupported
+		}
+		if strings.HasSuffix(opt, " ALL ") {
+			switch d {
+			case driver.SQLite, driver.MsSQL, driver.Doris:
+				return fmt.Errorf("union error:%sis not supported by %s", opt, d)
+			}
+		}
+	}
+	return nil
+}
+
+// operand writes a query of the set operation, it is parenthesized except on SQLite
+// which reads a nested set operation from a subquery instead
+func (b *UnionBuilder) operand(builder Builder, sqlite bool) string {
+	if !sqlite {
+		return "(" + b.Var(builder) + ")"
+	}
+	if _, ok := builder.(*UnionBuilder); ok {
+		return "SELECT * FROM (" + b.Var(builder) + ")"
+	}
+	return b.Var(builder)
+}
+
+func (b *UnionBuilder) build(blend bool, initial ...interface{}) (sql string, args []interface{}) {
 	estimatedSize := 256
 	if len(b.builders) > 0 {
 		estimatedSize += len(b.builders) * 50
@@ -108,32 +222,33 @@ func (b *UnionBuilder) build(blend bool) (sql string, args []interface{}) {
 
 	driverType := b.cond.driver.Value()
 
+	sqlite := driverType == driver.SQLite
 	if len(b.builders) > 0 {
-		needParen := driverType != driver.SQLite
+		buf.WriteString(b.operand(b.builders[0], sqlite))
 
-		if needParen {
-			buf.WriteRune('(')
+		for _, v := range b.builders[1:] {
+			buf.WriteString(b.opt)
+			buf.WriteString(b.operand(v, sqlite))
 		}
+	}
 
-		buf.WriteString(b.Var(b.builders[0]))
-
-		if needParen {
+	// the result so far is parenthesized when the operator changes, SQLite
+	// evaluates the operators from left to right and accepts no parentheses
+	opt, operands := b.opt, len(b.builders)
+	for _, s := range b.sets {
+		if s.opt != opt && !sqlite && operands > 1 {
+			combined := buf.String()
+			buf.Reset()
+			buf.WriteRune('(')
+			buf.WriteString(combined)
 			buf.WriteRune(')')
 		}
-
-		for _, v := range b.builders[1:] {
-			buf.WriteString(b.opt)
-
-			if needParen {
-				buf.WriteRune('(')
-			}
-
-			buf.WriteString(b.Var(v))
-
-			if needParen {
-				buf.WriteRune(')')
-			}
+		opt = s.opt
+		operands++
+		if buf.Len() > 0 {
+			buf.WriteString(s.opt)
 		}
+		buf.WriteString(b.operand(s.builder, sqlite))
 	}
 
 	if len(b.orderByCols) > 0 {
@@ -152,7 +267,22 @@ func (b *UnionBuilder) build(blend bool) (sql string, args []interface{}) {
 		}
 	}
 
-	if b.limit >= 0 {
+	if driverType == driver.MsSQL {
+		// SQL Server pages with OFFSET ... FETCH, which requires ORDER BY
+		if b.limit >= 0 || b.offset >= 0 {
+			if len(b.orderByCols) == 0 {
+				buf.WriteString(" ORDER BY 1")
+			}
+			buf.WriteString(" OFFSET ")
+			buf.WriteString(strconv.Itoa(max(b.offset, 0)))
+			buf.WriteString(" ROWS")
+		}
+		if b.limit >= 0 {
+			buf.WriteString(" FETCH NEXT ")
+			buf.WriteString(strconv.Itoa(b.limit))
+			buf.WriteString(" ROWS ONLY")
+		}
+	} else if b.limit >= 0 {
 		buf.WriteString(" LIMIT ")
 		buf.WriteString(strconv.Itoa(b.limit))
 	}
@@ -173,14 +303,6 @@ func (b *UnionBuilder) build(blend bool) (sql string, args []interface{}) {
 			// SQLite supports OFFSET without LIMIT (since version 3.8.0)
 			buf.WriteString(" OFFSET ")
 			buf.WriteString(strconv.Itoa(b.offset))
-		case driver.MsSQL:
-			// SQL Server uses OFFSET ROWS syntax, but only with ORDER BY
-			// Since this is UNION, we'll follow the same pattern as MySQL
-			if b.limit >= 0 {
-				buf.WriteString(" OFFSET ")
-				buf.WriteString(strconv.Itoa(b.offset))
-				buf.WriteString(" ROWS")
-			}
 		case driver.ClickHouse:
 			// ClickHouse supports OFFSET
 			buf.WriteString(" OFFSET ")
@@ -198,7 +320,7 @@ func (b *UnionBuilder) build(blend bool) (sql string, args []interface{}) {
 		return b.cond.CompileString(buf.String()), nil
 	}
 
-	return b.cond.Compile(buf.String())
+	return b.cond.Compile(buf.String(), initial...)
 }
 
 // Var returns a placeholder for value
@@ -211,7 +333,7 @@ func (b *UnionBuilder) Safety() error {
 	if len(b.builders) == 0 {
 		return errors.New("union safety error: no SELECT builders specified")
 	}
-	if len(b.builders) == 1 {
+	if len(b.builders) == 1 && len(b.sets) == 0 {
 		return errors.New("union safety warning: only one SELECT builder specified, UNION not needed")
 	}
 
@@ -223,3 +345,13 @@ func (b *UnionBuilder) Safety() error {
 
 	return nil
 }
+
+func (b *UnionBuilder) nestedBuild(parent *BuildCond, blend bool, initial []interface{}) (string, []interface{}, error) {
+	c := b.Clone()
+	c.cond.inherit(parent)
+	if err := c.check(); err != nil {
+		return "", initial, err
+	}
+	sql, values := c.build(blend, initial...)
+	return sql, values, c.cond.err
+}
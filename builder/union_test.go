@@ -1,12 +1,17 @@
 package builder_test
 
 import (
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"io"
 	"strings"
 	"testing"
 
 	"github.com/sohaha/zlsgo"
 	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver/mssql"
 	"github.com/zlsgo/zdb/driver/mysql"
+	"github.com/zlsgo/zdb/driver/postgres"
 )
 
 func TestUnion(t *testing.T) {
@@ -125,3 +130,108 @@ func TestUnionVar(t *testing.T) {
 	placeholder := union.Var("test_value")
 	tt.EqualTrue(placeholder != "")
 }
+
+func TestSetOperations(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	pg := &postgres.Config{}
+	query := func(table string, status int) *builder.SelectBuilder {
+		sb := builder.Query(table).Select("id").SetDriver(pg)
+		sb.Where(sb.Cond.EQ("status", status))
+		return sb
+	}
+
+	b := builder.Union(query("a", 1), query("b", 2)).SetDriver(pg)
+	b.Intersect(query("c", 3)).ExceptAll(builder.IntersectAll(query("d", 4), query("e", 5)))
+	b.OrderBy("id").Desc().Limit(10).Offset(20)
+	sql, values, err := b.Build()
+	tt.NoError(err)
+	tt.Equal(`(((SELECT "id" FROM "a" WHERE "status" = $1) UNION (SELECT "id" FROM "b" WHERE "status" = $2)) INTERSECT (SELECT "id" FROM "c" WHERE "status" = $3)) EXCEPT ALL ((SELECT "id" FROM "d" WHERE "status" = $4) INTERSECT ALL (SELECT "id" FROM "e" WHERE "status" = $5)) ORDER BY id DESC LIMIT 10 OFFSET 20`, sql)
+	tt.Equal([]interface{}{1, 2, 3, 4, 5}, values)
+
+	b = builder.Except(builder.Query("a").Select("id"), builder.Query("b").Select("id"))
+	b.Union(builder.Intersect(builder.Query("c").Select("id"), builder.Query("d").Select("id")))
+	sql, _, err = b.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT "id" FROM "a" EXCEPT SELECT "id" FROM "b" UNION SELECT * FROM (SELECT "id" FROM "c" INTERSECT SELECT "id" FROM "d")`, sql)
+
+	_, _, err = builder.IntersectAll(builder.Query("a"), builder.Query("b")).Build()
+	tt.EqualTrue(err != nil)
+
+	old := &mysql.Config{Version: "8.0.30"}
+	_, _, err = builder.Except(builder.Query("a").SetDriver(old), builder.Query("b").SetDriver(old)).SetDriver(old).Build()
+	tt.EqualTrue(err != nil)
+	_, _, err = builder.Union(builder.Query("a"), builder.Query("b")).SetDriver(old).Build()
+	tt.NoError(err)
+
+	ms := &mssql.Config{}
+	b = builder.Intersect(builder.Query("a").Select("id").SetDriver(ms), builder.Query("b").Select("id").SetDriver(ms)).SetDriver(ms)
+	sql, _, err = b.Limit(5).Offset(10).Build()
+	tt.NoError(err)
+	tt.Equal(`(SELECT "id" FROM "a") INTERSECT (SELECT "id" FROM "b") ORDER BY 1 OFFSET 10 ROWS FETCH NEXT 5 ROWS ONLY`, sql)
+	sql, _, err = b.OrderBy("id").Limit(5).Offset(-1).Build()
+	tt.NoError(err)
+	tt.Equal(`(SELECT "id" FROM "a") INTERSECT (SELECT "id" FROM "b") ORDER BY id OFFSET 0 ROWS FETCH NEXT 5 ROWS ONLY`, sql)
+}
+
+// versionServer is a database/sql driver answering every query with the version it is opened with
+type versionServer struct{}
+
+type versionConn struct{ version string }
+
+type versionRows struct {
+	version string
+	done    bool
+}
+
+func (versionServer) Open(name string) (sqldriver.Conn, error) {
+	return versionConn{version: name}, nil
+}
+
+func (c versionConn) Prepare(string) (sqldriver.Stmt, error) { return c, nil }
+func (versionConn) Close() error                             { return nil }
+func (versionConn) Begin() (sqldriver.Tx, error)             { return nil, io.EOF }
+func (versionConn) NumInput() int                            { return 0 }
+func (versionConn) Exec([]sqldriver.Value) (sqldriver.Result, error) {
+	return nil, io.EOF
+}
+
+func (c versionConn) Query([]sqldriver.Value) (sqldriver.Rows, error) {
+	return &versionRows{version: c.version}, nil
+}
+
+func (*versionRows) Columns() []string { return []string{"version"} }
+func (*versionRows) Close() error      { return nil }
+func (r *versionRows) Next(dest []sqldriver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.version
+	return nil
+}
+
+func TestUnionDetectedVersion(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sql.Register("union_version", versionServer{})
+	detect := func(version string) *mysql.Config {
+		db, err := sql.Open("union_version", version)
+		tt.NoError(err)
+		defer db.Close()
+
+		conf := &mysql.Config{}
+		tt.NoError(conf.DetectVersion(db))
+		tt.Equal(version, conf.Version)
+		return conf
+	}
+
+	old := detect("8.0.30-log")
+	_, _, err := builder.Intersect(builder.Query("a").SetDriver(old), builder.Query("b").SetDriver(old)).SetDriver(old).Build()
+	tt.EqualTrue(err != nil)
+
+	current := detect("8.0.31")
+	query, _, err := builder.Intersect(builder.Query("a").SetDriver(current), builder.Query("b").SetDriver(current)).SetDriver(current).Build()
+	tt.NoError(err)
+	tt.Equal("(SELECT * FROM `a`) INTERSECT (SELECT * FROM `b`)", query)
+}
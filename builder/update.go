@@ -1,8 +1,10 @@
 package builder
 
 import (
+	"bytes"
 	"errors"
 	"strconv"
+	"strings"
 
 	"github.com/sohaha/zlsgo/zutil"
 	"github.com/zlsgo/zdb/driver"
@@ -11,12 +13,15 @@ import (
 // UpdateBuilder is a builder to build UPDATE
 type UpdateBuilder struct {
 	Cond        *BuildCond
+	err         error
 	table       string
 	order       string
 	assignments []string
 	whereExprs  []string
 	orderByCols []string
 	options     [][]string
+	sources     sources
+	with        withClause
 	limit       int
 	allowEmpty  bool
 	limitBy     string
@@ -40,6 +45,36 @@ func (b *UpdateBuilder) SetDriver(driver driver.Dialect) *UpdateBuilder {
 	return b
 }
 
+// With adds a common table expression before the UPDATE, cols optionally names its columns
+func (b *UpdateBuilder) With(name string, builder Builder, cols ...string) *UpdateBuilder {
+	b.with.add(false, name, builder, cols)
+	return b
+}
+
+// WithRecursive adds a recursive common table expression before the UPDATE
+func (b *UpdateBuilder) WithRecursive(name string, builder Builder, cols ...string) *UpdateBuilder {
+	b.with.add(true, name, builder, cols)
+	return b
+}
+
+// Join adds an inner JOIN to the tables the UPDATE reads,
+// MySQL writes it before SET, PostgreSQL and SQLite move it to FROM with the ON expressions in WHERE
+func (b *UpdateBuilder) Join(table string, onExpr ...string) *UpdateBuilder {
+	return b.JoinWithOption("", table, onExpr...)
+}
+
+// JoinWithOption adds a JOIN with option, only inner joins are supported by PostgreSQL and SQLite
+func (b *UpdateBuilder) JoinWithOption(option JoinOption, table string, onExpr ...string) *UpdateBuilder {
+	b.sources.join(option, table, onExpr)
+	return b
+}
+
+// From adds tables the UPDATE reads, they are joined by the conditions in WHERE
+func (b *UpdateBuilder) From(table ...string) *UpdateBuilder {
+	b.sources.tables = append(b.sources.tables, table...)
+	return b
+}
+
 // Set sets the assignments in SET
 func (b *UpdateBuilder) Set(assignment ...string) *UpdateBuilder {
 	b.assignments = assignment
@@ -58,6 +93,17 @@ func (b *UpdateBuilder) Where(andExpr ...string) *UpdateBuilder {
 	return b
 }
 
+// WhereBy adds the conditions of a map or a filter struct to WHERE, see BuildCond.Parse
+func (b *UpdateBuilder) WhereBy(cond interface{}) *UpdateBuilder {
+	expr, err := b.Cond.Parse(cond)
+	if err != nil {
+		b.err = err
+	} else if expr != "" {
+		b.whereExprs = append(b.whereExprs, expr)
+	}
+	return b
+}
+
 // Assign represents SET "field = value" in UPDATE
 func (b *UpdateBuilder) Assign(field string, value interface{}) string {
 	return b.Cond.Cond(field, " = ", value)
@@ -141,22 +187,36 @@ func (b *UpdateBuilder) String() string {
 
 // Build returns compiled UPDATE string and Cond
 func (b *UpdateBuilder) Build() (sql string, value []interface{}, err error) {
-	if len(b.whereExprs) == 0 {
-		return "", nil, errors.New("update safety error: no where condition")
-	}
-	if b.limit >= 0 && b.Cond.driver.Value() != driver.MySQL && b.limitBy == "" {
-		return "", nil, errors.New("update safety error: limit requires LimitBy for non-MySQL")
+	if err = b.check(); err != nil {
+		return "", nil, err
 	}
 
 	sql, value = b.build(false)
+	if b.Cond.err != nil {
+		return "", nil, b.Cond.err
+	}
 	return
 }
 
+// check returns the errors that keep the UPDATE from being run
+func (b *UpdateBuilder) check() error {
+	if b.err != nil {
+		return b.err
+	}
+	if len(b.whereExprs) == 0 {
+		return errors.New("update safety error: no where condition")
+	}
+	if b.limit >= 0 && b.Cond.driver.Value() != driver.MySQL && b.limitBy == "" {
+		return errors.New("update safety error: limit requires LimitBy for non-MySQL")
+	}
+	return b.sources.check("update", b.Cond.driver.Value(), b.limit)
+}
+
 func (b *UpdateBuilder) buildStatement() []byte {
 	return buildWhereOrderStatement(b.Cond, b.whereExprs, b.orderByCols, b.order)
 }
 
-func (b *UpdateBuilder) build(blend bool) (sql string, args []interface{}) {
+func (b *UpdateBuilder) build(blend bool, initial ...interface{}) (sql string, args []interface{}) {
 	// More accurate size estimation
 	estimatedSize := 7 + len(b.table) + 4 // "UPDATE " + table + quotes
 	estimatedSize += 5 // " SET "
@@ -194,46 +254,45 @@ func (b *UpdateBuilder) build(blend bool) (sql string, args []interface{}) {
 		}
 	}
 
+	prefix, initial := b.with.build(b.Cond, blend, initial)
+
 	buf := zutil.GetBuff(uint(estimatedSize))
 	defer zutil.PutBuff(buf)
 
 	driverValue := b.Cond.driver.Value()
 
 	buf.WriteString("UPDATE ")
-	buf.WriteString(driverValue.Quote(b.table))
-
-	buf.WriteString(" SET ")
-	for i, assignment := range b.assignments {
-		if i > 0 {
-			buf.WriteString(", ")
-		}
-		buf.WriteString(assignment)
-	}
-
-	if b.limit >= 0 {
-		if driverValue != driver.MySQL {
-			limitByQuoted := driverValue.Quote(b.limitBy)
-			buf.WriteString(" WHERE ")
-			buf.WriteString(limitByQuoted)
-			buf.WriteString(" IN (")
-
-			buf.WriteString("SELECT ")
-			buf.WriteString(limitByQuoted)
-			buf.WriteString(" FROM ")
-			buf.WriteString(driverValue.Quote(b.table))
-			buf.Write(b.buildStatement())
-			buf.WriteString(" LIMIT ")
-			buf.WriteString(strconv.Itoa(b.limit))
-
-			buf.WriteString(")")
+	if !b.sources.empty() {
+		b.buildJoined(buf, driverValue)
+	} else {
+		buf.WriteString(b.Cond.quote(b.table))
+		b.buildSet(buf, "")
+
+		if b.limit >= 0 {
+			if driverValue != driver.MySQL {
+				limitByQuoted := b.Cond.quote(b.limitBy)
+				buf.WriteString(" WHERE ")
+				buf.WriteString(limitByQuoted)
+				buf.WriteString(" IN (")
+
+				buf.WriteString("SELECT ")
+				buf.WriteString(limitByQuoted)
+				buf.WriteString(" FROM ")
+				buf.WriteString(b.Cond.quote(b.table))
+				buf.Write(b.buildStatement())
+				buf.WriteString(" LIMIT ")
+				buf.WriteString(strconv.Itoa(b.limit))
+
+				buf.WriteString(")")
+			} else {
+				buf.Write(b.buildStatement())
+
+				buf.WriteString(" LIMIT ")
+				buf.WriteString(strconv.Itoa(b.limit))
+			}
 		} else {
 			buf.Write(b.buildStatement())
-
-			buf.WriteString(" LIMIT ")
-			buf.WriteString(strconv.Itoa(b.limit))
 		}
-	} else {
-		buf.Write(b.buildStatement())
 	}
 
 	if len(b.options) > 0 {
@@ -254,8 +313,58 @@ func (b *UpdateBuilder) build(blend bool) (sql string, args []interface{}) {
 	}
 
 	if blend {
-		return b.Cond.CompileString(buf.String()), nil
+		return prefix + b.Cond.CompileString(buf.String(), initial...), nil
 	}
 
-	return b.Cond.Compile(buf.String())
+	sql, args = b.Cond.Compile(buf.String(), initial...)
+	return prefix + sql, args
+}
+
+// buildSet writes SET, the assignments of the columns of target are written unqualified when target is set
+func (b *UpdateBuilder) buildSet(buf *bytes.Buffer, target string) {
+	buf.WriteString(" SET ")
+	for i, assignment := range b.assignments {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if target != "" {
+			assignment = unqualify(assignment, target)
+		}
+		buf.WriteString(assignment)
+	}
+}
+
+// buildJoined writes the target, the joined tables, SET and WHERE in the order of the dialect
+func (b *UpdateBuilder) buildJoined(buf *bytes.Buffer, d driver.Typ) {
+	whereExprs := b.whereExprs
+	switch d {
+	case driver.MsSQL:
+		buf.WriteString(targetName(b.Cond, b.table))
+		b.buildSet(buf, "")
+		buf.WriteString(" FROM ")
+		buf.WriteString(b.Cond.quote(b.table))
+		b.sources.write(buf, b.Cond)
+	case driver.PostgreSQL, driver.SQLite, driver.Doris:
+		buf.WriteString(aliasTable(b.Cond, b.table))
+		b.buildSet(buf, targetName(b.Cond, b.table))
+		tables, conds := b.sources.flatten(b.Cond)
+		buf.WriteString(" FROM ")
+		buf.WriteString(strings.Join(tables, ", "))
+		whereExprs = append(conds, whereExprs...)
+	default:
+		buf.WriteString(b.Cond.quote(b.table))
+		b.sources.write(buf, b.Cond)
+		b.buildSet(buf, "")
+	}
+	buf.Write(buildWhereOrderStatement(b.Cond, whereExprs, b.orderByCols, b.order))
+}
+
+func (b *UpdateBuilder) nestedBuild(parent *BuildCond, blend bool, initial []interface{}) (string, []interface{}, error) {
+	c := b.Clone()
+	c.Cond.inherit(parent)
+	if err := c.check(); err != nil {
+		return "", initial, err
+	}
+	sql, values := c.build(blend, initial...)
+	return sql, values, c.Cond.err
 }
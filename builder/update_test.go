@@ -6,7 +6,10 @@ import (
 
 	"github.com/sohaha/zlsgo"
 	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver"
+	"github.com/zlsgo/zdb/driver/mssql"
 	"github.com/zlsgo/zdb/driver/mysql"
+	"github.com/zlsgo/zdb/driver/postgres"
 	"github.com/zlsgo/zdb/driver/sqlite3"
 )
 
@@ -140,3 +143,49 @@ func TestUpdateNoWhere(t *testing.T) {
 	_, _, err := u.Build()
 	tt.EqualTrue(err != nil)
 }
+
+func TestUpdateJoin(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	build := func(d driver.Dialect) (string, []interface{}, error) {
+		ub := builder.Update("user u").SetDriver(d)
+		ub.Set(ub.Assign("u.level", builder.Column("v.level")), ub.Assign("u.flag", 1))
+		ub.Join("vip v", "v.user_id = u.id").Where(ub.Cond.GT("v.score", 10))
+		return ub.Build()
+	}
+
+	sql, values, err := build(&mysql.Config{})
+	tt.NoError(err)
+	tt.Equal("UPDATE `user` u JOIN vip v ON v.user_id = u.id SET `u`.`level` = `v`.`level`, `u`.`flag` = ? WHERE `v`.`score` > ?", sql)
+	tt.Equal([]interface{}{1, 10}, values)
+
+	sql, values, err = build(&postgres.Config{})
+	tt.NoError(err)
+	tt.Equal(`UPDATE "user" AS u SET "level" = "v"."level", "flag" = $1 FROM vip v WHERE v.user_id = u.id AND "v"."score" > $2`, sql)
+	tt.Equal([]interface{}{1, 10}, values)
+
+	sql, _, err = build(&sqlite3.Config{})
+	tt.NoError(err)
+	tt.Equal(`UPDATE "user" AS u SET "level" = "v"."level", "flag" = ? FROM vip v WHERE v.user_id = u.id AND "v"."score" > ?`, sql)
+
+	sql, values, err = build(&mssql.Config{})
+	tt.NoError(err)
+	tt.Equal(`UPDATE u SET "u"."level" = "v"."level", "u"."flag" = @p1 FROM "user" u JOIN vip v ON v.user_id = u.id WHERE "v"."score" > @p2`, sql)
+	tt.Equal([]interface{}{1, 10}, values)
+
+	ub := builder.Update("user").SetDriver(&postgres.Config{})
+	ub.Set(ub.Assign("user.status", 0)).From("ban").Where("ban.user_id = \"user\".id")
+	sql, _, err = ub.Build()
+	tt.NoError(err)
+	tt.Equal(`UPDATE "user" SET "status" = $1 FROM "ban" WHERE ban.user_id = "user".id`, sql)
+
+	ub = builder.Update("user").SetDriver(&postgres.Config{})
+	ub.Set(ub.Assign("status", 0)).JoinWithOption(builder.LeftJoin, "vip", "vip.user_id = user.id").Where("vip.id IS NULL")
+	_, _, err = ub.Build()
+	tt.EqualTrue(err != nil)
+
+	ub = builder.Update("user").SetDriver(&mysql.Config{})
+	ub.Set(ub.Assign("status", 0)).Join("vip", "vip.user_id = user.id").Where("vip.id > 1").Limit(1)
+	_, _, err = ub.Build()
+	tt.EqualTrue(err != nil)
+}
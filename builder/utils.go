@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/sohaha/zlsgo/ztype"
 	"github.com/sohaha/zlsgo/zutil"
 	"github.com/zlsgo/zdb/driver"
 )
@@ -28,45 +29,81 @@ func EscapeAll(ident ...string) []string {
 	return escaped
 }
 
-type rawArgs struct {
-	expr string
-}
+type (
+	rawArgs struct {
+		expr string
+	}
+	columnArgs struct {
+		name string
+	}
+)
 
 // Raw marks the expr as a raw value which will not be added to Cond.
 func Raw(expr string) interface{} {
 	return rawArgs{expr}
 }
 
+// Column marks the name as a column which is quoted by the dialect instead of bound as a value,
+// such as the outer alias "u.id" referenced by a correlated subquery
+func Column(name string) interface{} {
+	return columnArgs{name}
+}
+
+func (c columnArgs) String() string {
+	return c.name
+}
+
 // Named creates a named argument
 func Named(name string, arg interface{}) interface{} {
 	return zutil.Named(name, arg)
 }
 
 func argsCompileHandler(args *BuildCond) zutil.ArgsOpt {
-	return zutil.WithCompileHandler(func(buf *bytes.Buffer, values []interface{}, arg interface{}) ([]interface{}, bool) {
-		driverType := args.driver.Value()
+	return zutil.WithCompileHandler(args.compileHandler(false))
+}
+
+// compileHandler writes the arguments as placeholders, or as their text when blend is set
+func (c *BuildCond) compileHandler(blend bool) zutil.ArgsCompileHandler {
+	write := writeVar
+	if blend {
+		write = writeString
+	}
+	var handle zutil.ArgsCompileHandler
+	handle = func(buf *bytes.Buffer, values []interface{}, arg interface{}) ([]interface{}, bool) {
+		driverType := c.driver.Value()
 		switch a := arg.(type) {
 		case Builder:
-			s, args, _ := a.Build()
+			var s string
+			s, values = c.buildNested(a, blend, values)
 			buf.WriteString(s)
-
-			if argsLen := len(args); argsLen > 0 {
-				valuesLen := len(values)
-				neededCap := valuesLen + argsLen
-				if cap(values) < neededCap {
-					newValues := make([]interface{}, valuesLen, neededCap)
-					copy(newValues, values)
-					values = newValues
-				}
-
-				values = append(values, args...)
-			}
 			return values, true
 		case rawArgs:
 			buf.WriteString(a.expr)
 			return values, true
+		case columnArgs:
+			buf.WriteString(c.quote(a.name))
+			return values, true
+		case jsonArgs:
+			if a.alias == "" {
+				return a.build(buf, driverType, c.quote(a.field), values, write), true
+			}
+			buf.WriteByte('(')
+			values = a.build(buf, driverType, c.quote(a.field), values, write)
+			buf.WriteString(") AS ")
+			buf.WriteString(c.quote(a.alias))
+			return values, true
+		case Expr:
+			w := &exprWriter{buf: buf, d: driverType, values: values, handle: handle, compile: func(format string, values []interface{}) (string, []interface{}) {
+				args := c.Args
+				zutil.WithCompileHandler(c.compileHandler(blend))(&args)
+				return args.Compile(format, values...)
+			}}
+			if err := a.build(w); err != nil && c.err == nil {
+				c.err = err
+			}
+			return w.values, true
 		case sql.NamedArg:
-			if driverType == driver.MsSQL {
+			if driverType == driver.MsSQL && !blend {
 				buf.WriteRune('@')
 				buf.WriteString(a.Name)
 				return values, true
@@ -74,20 +111,31 @@ func argsCompileHandler(args *BuildCond) zutil.ArgsOpt {
 			arg = a.Value
 		}
 
-		switch driverType {
-		case driver.PostgreSQL:
-			buf.WriteRune('$')
-			buf.WriteString(strconv.Itoa(len(values) + 1))
-		case driver.MsSQL:
-			buf.WriteString("@p")
-			buf.WriteString(strconv.Itoa(len(values) + 1))
-		default:
-			buf.WriteRune('?')
-		}
+		return write(buf, driverType, values, c.encode(arg)), true
+	}
+	return handle
+}
+
+// writeVar writes the placeholder of arg in the dialect and appends arg to values
+func writeVar(buf *bytes.Buffer, driverType driver.Typ, values []interface{}, arg interface{}) []interface{} {
+	switch driverType {
+	case driver.PostgreSQL:
+		buf.WriteRune('$')
+		buf.WriteString(strconv.Itoa(len(values) + 1))
+	case driver.MsSQL:
+		buf.WriteString("@p")
+		buf.WriteString(strconv.Itoa(len(values) + 1))
+	default:
+		buf.WriteRune('?')
+	}
+
+	return append(values, arg)
+}
 
-		values = append(values, arg)
-		return values, true
-	})
+// writeString writes the text of arg, it is used by CompileString for debugging
+func writeString(buf *bytes.Buffer, _ driver.Typ, values []interface{}, arg interface{}) []interface{} {
+	buf.WriteString(ztype.ToString(arg))
+	return values
 }
 
 // buildWhereOrderStatement builds common WHERE and ORDER BY statements for UPDATE and DELETE
@@ -102,7 +150,7 @@ func buildWhereOrderStatement(cond *BuildCond, whereExprs []string, orderByCols
 
 	if len(orderByCols) > 0 {
 		buf.WriteString(" ORDER BY ")
-		buf.WriteString(strings.Join(cond.driver.Value().QuoteCols(orderByCols), ", "))
+		buf.WriteString(strings.Join(cond.quoteOrderCols(orderByCols), ", "))
 
 		if order != "" {
 			buf.WriteRune(' ')
@@ -0,0 +1,274 @@
+package builder
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/sohaha/zlsgo/zstring"
+)
+
+const (
+	condOr  = "$or"
+	condAnd = "$and"
+)
+
+var (
+	errCondInvalid  = errors.New("condition must be a map or a struct")
+	errCondOperator = errors.New("condition operator is not supported")
+	errCondBetween  = errors.New("between condition requires two values")
+)
+
+// Parse compiles a declarative condition into an expression joined with AND.
+// cond is either a map such as
+//
+//	{"age >=": 18, "status": []int{1, 2}, "name like": "%a%", "deleted_at": nil,
+//	 "$or": []map[string]interface{}{{"role": 1}, {"vip": true}}}
+//
+// or a filter struct whose fields are tagged like `zdb:"age,>="`, `zdb:"name,like"`,
+// zero fields of a struct are skipped and a struct field tagged `zdb:",or"` is a nested OR group
+func (c *BuildCond) Parse(cond interface{}) (string, error) {
+	exprs, err := c.parseCond(reflect.ValueOf(cond))
+	if err != nil || len(exprs) == 0 {
+		return "", err
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return strings.Join(exprs, " AND "), nil
+}
+
+func (c *BuildCond) parseCond(v reflect.Value) ([]string, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		return c.parseMap(v)
+	case reflect.Struct:
+		return c.parseStruct(v)
+	}
+	return nil, errCondInvalid
+}
+
+func (c *BuildCond) parseMap(v reflect.Value) ([]string, error) {
+	if v.Type().Key().Kind() != reflect.String {
+		return nil, errCondInvalid
+	}
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+
+	exprs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		val := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+		var (
+			expr string
+			err  error
+		)
+		switch strings.ToLower(key) {
+		case condOr:
+			expr, err = c.parseGroup(val, true)
+		case condAnd:
+			expr, err = c.parseGroup(val, false)
+		default:
+			field, op := splitCondKey(key)
+			expr, err = c.compare(field, op, val.Interface())
+		}
+		if err != nil {
+			return nil, err
+		}
+		if expr != "" {
+			exprs = append(exprs, expr)
+		}
+	}
+	return exprs, nil
+}
+
+// parseGroup joins a map or a list of conditions with OR or AND
+func (c *BuildCond) parseGroup(v reflect.Value, or bool) (string, error) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	var exprs []string
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			items, err := c.parseCond(v.Index(i))
+			if err != nil {
+				return "", err
+			}
+			switch len(items) {
+			case 0:
+			case 1:
+				exprs = append(exprs, items[0])
+			default:
+				exprs = append(exprs, c.And(items...))
+			}
+		}
+	} else {
+		var err error
+		if exprs, err = c.parseCond(v); err != nil {
+			return "", err
+		}
+	}
+
+	if len(exprs) == 0 {
+		return "", nil
+	}
+	if or {
+		return c.Or(exprs...), nil
+	}
+	return c.And(exprs...), nil
+}
+
+func (c *BuildCond) parseStruct(v reflect.Value) ([]string, error) {
+	typ := v.Type()
+	exprs := make([]string, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		name, op := field.Tag.Get("zdb"), ""
+		if name == "-" {
+			continue
+		}
+		if j := strings.IndexByte(name, ','); j >= 0 {
+			name, op = name[:j], strings.TrimSpace(name[j+1:])
+		}
+
+		fv := v.Field(i)
+		lop := strings.ToLower(op)
+		if field.Anonymous || lop == "or" || lop == "and" {
+			if fv.IsZero() {
+				continue
+			}
+			if lop == "or" || lop == "and" {
+				expr, err := c.parseGroup(fv, lop == "or")
+				if err != nil {
+					return nil, err
+				}
+				if expr != "" {
+					exprs = append(exprs, expr)
+				}
+				continue
+			}
+			items, err := c.parseCond(fv)
+			if err != nil {
+				return nil, err
+			}
+			exprs = append(exprs, items...)
+			continue
+		}
+
+		if fv.IsZero() {
+			continue
+		}
+		if fv.Kind() == reflect.Slice && fv.Len() == 0 {
+			continue
+		}
+		if name == "" {
+			name = zstring.CamelCaseToSnakeCase(field.Name)
+		}
+		if fv.Kind() == reflect.Ptr {
+			fv = fv.Elem()
+		}
+		expr, err := c.compare(name, lop, fv.Interface())
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	return exprs, nil
+}
+
+func splitCondKey(key string) (field, op string) {
+	key = strings.TrimSpace(key)
+	if i := strings.IndexByte(key, ' '); i > 0 {
+		return key[:i], strings.ToLower(strings.Join(strings.Fields(key[i+1:]), " "))
+	}
+	return key, ""
+}
+
+// compare compiles a single condition, nil becomes IS NULL and lists become IN
+func (c *BuildCond) compare(field, op string, value interface{}) (string, error) {
+	list, isList := condList(value)
+	switch op {
+	case "", "=", "==", "eq", "is":
+		if value == nil {
+			return c.IsNull(field), nil
+		}
+		if isList {
+			return c.In(field, list...), nil
+		}
+		return c.EQ(field, value), nil
+	case "!=", "<>", "ne", "is not":
+		if value == nil {
+			return c.IsNotNull(field), nil
+		}
+		if isList {
+			return c.NotIn(field, list...), nil
+		}
+		return c.NE(field, value), nil
+	case ">", "gt":
+		return c.GT(field, value), nil
+	case ">=", "gte", "ge":
+		return c.GE(field, value), nil
+	case "<", "lt":
+		return c.LT(field, value), nil
+	case "<=", "lte", "le":
+		return c.LE(field, value), nil
+	case "like":
+		return c.Like(field, value), nil
+	case "not like":
+		return c.NotLike(field, value), nil
+	case "in":
+		if !isList {
+			list = []interface{}{value}
+		}
+		return c.In(field, list...), nil
+	case "not in", "nin":
+		if !isList {
+			list = []interface{}{value}
+		}
+		return c.NotIn(field, list...), nil
+	case "between", "not between":
+		if len(list) != 2 {
+			return "", errCondBetween
+		}
+		if op == "between" {
+			return c.Between(field, list[0], list[1]), nil
+		}
+		return c.NotBetween(field, list[0], list[1]), nil
+	}
+	return "", errors.New(errCondOperator.Error() + ": " + op)
+}
+
+// condList expands slices and arrays other than []byte into values
+func condList(value interface{}) ([]interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+	if _, ok := value.([]byte); ok {
+		return nil, false
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+	list := make([]interface{}, v.Len())
+	for i := range list {
+		list[i] = v.Index(i).Interface()
+	}
+	return list, true
+}
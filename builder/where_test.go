@@ -0,0 +1,112 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver/postgres"
+)
+
+func TestWhereByMap(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sb := builder.Query("user").Select("id")
+	sb.WhereBy(map[string]interface{}{
+		"age >=":     18,
+		"status":     []int{1, 2},
+		"name like":  "%a%",
+		"deleted_at": nil,
+		"$or": []map[string]interface{}{
+			{"role": "admin"},
+			{"vip": true, "score >": 100},
+		},
+	})
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT "id" FROM "user" WHERE ("role" = ? OR ("score" > ? AND "vip" = ?)) AND "age" >= ? AND "deleted_at" IS NULL AND "name" LIKE ? AND "status" IN (?, ?)`, sql)
+	tt.Equal([]interface{}{"admin", 100, true, 18, "%a%", 1, 2}, values)
+
+	sb = builder.Query("user").SetDriver(&postgres.Config{})
+	sb.Where(sb.Cond.EQ("tenant", 7)).WhereBy(map[string]interface{}{
+		"id between": []int{1, 10},
+		"email !=":   nil,
+		"type nin":   []string{"a", "b"},
+	})
+	sql, values, err = sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" WHERE "tenant" = $1 AND "email" IS NOT NULL AND "id" BETWEEN $2 AND $3 AND "type" NOT IN ($4, $5)`, sql)
+	tt.Equal([]interface{}{7, 1, 10, "a", "b"}, values)
+
+	_, _, err = builder.Query("user").WhereBy(map[string]interface{}{"age ~": 1}).Build()
+	tt.EqualTrue(err != nil)
+
+	_, _, err = builder.Query("user").WhereBy(map[string]interface{}{"age between": 1}).Build()
+	tt.EqualTrue(err != nil)
+
+	_, _, err = builder.Query("user").WhereBy([]int{1}).Build()
+	tt.EqualTrue(err != nil)
+}
+
+func TestWhereByStruct(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	type keyword struct {
+		Name  string `zdb:"name,like"`
+		Email string `zdb:"email,like"`
+	}
+	type page struct {
+		Size int
+	}
+	type filter struct {
+		page
+		Keyword  keyword `zdb:",or"`
+		MinAge   int     `zdb:"age,>="`
+		Status   []int   `zdb:"status"`
+		Verified *bool   `zdb:"verified"`
+		Ignored  string  `zdb:"-"`
+		UserType string
+	}
+
+	verified := false
+	sb := builder.Query("user")
+	sb.WhereBy(&filter{
+		Keyword:  keyword{Name: "%a%", Email: "%a%"},
+		MinAge:   18,
+		Verified: &verified,
+		Ignored:  "x",
+		UserType: "staff",
+	})
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user" WHERE ("name" LIKE ? OR "email" LIKE ?) AND "age" >= ? AND "verified" = ? AND "user_type" = ?`, sql)
+	tt.Equal([]interface{}{"%a%", "%a%", 18, false, "staff"}, values)
+
+	sb = builder.Query("user")
+	sql, _, err = sb.WhereBy(filter{}).Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "user"`, sql)
+}
+
+func TestWhereByWrite(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	ub := builder.Update("user")
+	ub.Set(ub.Assign("status", 0)).WhereBy(map[string]interface{}{"id in": []int{1, 2}})
+	sql, values, err := ub.Build()
+	tt.NoError(err)
+	tt.Equal(`UPDATE "user" SET "status" = ? WHERE "id" IN (?, ?)`, sql)
+	tt.Equal([]interface{}{0, 1, 2}, values)
+
+	db := builder.Delete("user").WhereBy(map[string]interface{}{"expired_at <": 100})
+	sql, values, err = db.Build()
+	tt.NoError(err)
+	tt.Equal(`DELETE FROM "user" WHERE "expired_at" < ?`, sql)
+	tt.Equal([]interface{}{100}, values)
+
+	_, _, err = builder.Delete("user").WhereBy(map[string]interface{}{}).Build()
+	tt.EqualTrue(err != nil)
+
+	_, _, err = builder.Update("user").WhereBy(map[string]interface{}{"id like like": 1}).Build()
+	tt.EqualTrue(err != nil)
+}
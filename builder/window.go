@@ -0,0 +1,182 @@
+package builder
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/zlsgo/zdb/driver"
+)
+
+type (
+	// WindowSpec is the window of an OVER clause or of a named WINDOW
+	WindowSpec struct {
+		base      string
+		frame     string
+		partition []string
+		orders    []string
+	}
+	namedWindow struct {
+		spec *WindowSpec
+		name string
+	}
+)
+
+// Frame bounds used by WindowSpec.Rows and WindowSpec.Range
+const (
+	UnboundedPreceding = "UNBOUNDED PRECEDING"
+	UnboundedFollowing = "UNBOUNDED FOLLOWING"
+	CurrentRow         = "CURRENT ROW"
+)
+
+var (
+	errWindowUnsupported      = errors.New("select error: window functions require MySQL 8.0")
+	errNamedWindowUnsupported = errors.New("select error: named WINDOW requires SQL Server 2022")
+)
+
+// Window returns a window specification, base optionally names the window it refines
+func Window(base ...string) *WindowSpec {
+	w := &WindowSpec{}
+	if len(base) > 0 {
+		w.base = base[0]
+	}
+	return w
+}
+
+// Preceding returns the frame bound of n rows before the current row
+func Preceding(n int) string {
+	return strconv.Itoa(n) + " PRECEDING"
+}
+
+// Following returns the frame bound of n rows after the current row
+func Following(n int) string {
+	return strconv.Itoa(n) + " FOLLOWING"
+}
+
+// PartitionBy sets the columns of PARTITION BY
+func (w *WindowSpec) PartitionBy(col ...string) *WindowSpec {
+	w.partition = append(w.partition, col...)
+	return w
+}
+
+// OrderBy sets the columns of ORDER BY, such as "created_at DESC"
+func (w *WindowSpec) OrderBy(col ...string) *WindowSpec {
+	w.orders = append(w.orders, col...)
+	return w
+}
+
+// Rows sets the frame to ROWS BETWEEN start AND end
+func (w *WindowSpec) Rows(start, end string) *WindowSpec {
+	w.frame = "ROWS BETWEEN " + start + " AND " + end
+	return w
+}
+
+// Range sets the frame to RANGE BETWEEN start AND end
+func (w *WindowSpec) Range(start, end string) *WindowSpec {
+	w.frame = "RANGE BETWEEN " + start + " AND " + end
+	return w
+}
+
+// build returns the window with its columns quoted by c
+func (w *WindowSpec) build(c *BuildCond) string {
+	parts := make([]string, 0, 4)
+	if w.base != "" {
+		parts = append(parts, c.quote(Escape(w.base)))
+	}
+	if len(w.partition) > 0 {
+		parts = append(parts, "PARTITION BY "+strings.Join(c.quoteCols(EscapeAll(w.partition...)), ", "))
+	}
+	if len(w.orders) > 0 {
+		parts = append(parts, "ORDER BY "+strings.Join(c.quoteOrderCols(EscapeAll(w.orders...)), ", "))
+	}
+	if w.frame != "" {
+		parts = append(parts, w.frame)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Over returns "expr OVER (window)", a window with only a base name refers to the named window
+func (b *SelectBuilder) Over(expr string, w *WindowSpec) string {
+	b.windowed = true
+	if w == nil {
+		return expr + " OVER ()"
+	}
+	if w.base != "" && len(w.partition) == 0 && len(w.orders) == 0 && w.frame == "" {
+		return expr + " OVER " + b.Cond.quote(Escape(w.base))
+	}
+	return expr + " OVER (" + w.build(b.Cond) + ")"
+}
+
+// RowNumber represents ROW_NUMBER() OVER (window)
+func (b *SelectBuilder) RowNumber(w *WindowSpec) string {
+	return b.Over("ROW_NUMBER()", w)
+}
+
+// Rank represents RANK() OVER (window)
+func (b *SelectBuilder) Rank(w *WindowSpec) string {
+	return b.Over("RANK()", w)
+}
+
+// DenseRank represents DENSE_RANK() OVER (window)
+func (b *SelectBuilder) DenseRank(w *WindowSpec) string {
+	return b.Over("DENSE_RANK()", w)
+}
+
+// Lag represents LAG(field, offset) OVER (window), offset 0 uses the default of 1
+func (b *SelectBuilder) Lag(field string, offset int, w *WindowSpec) string {
+	return b.Over(b.offsetFunc("LAG", field, offset), w)
+}
+
+// Lead represents LEAD(field, offset) OVER (window), offset 0 uses the default of 1
+func (b *SelectBuilder) Lead(field string, offset int, w *WindowSpec) string {
+	return b.Over(b.offsetFunc("LEAD", field, offset), w)
+}
+
+// Aggregate represents fn(field) OVER (window), such as SUM("amount") OVER (...)
+func (b *SelectBuilder) Aggregate(fn, field string, w *WindowSpec) string {
+	return b.Over(fn+"("+b.Cond.quoteField(field)+")", w)
+}
+
+// Window adds a named window to the WINDOW clause in SELECT
+func (b *SelectBuilder) Window(name string, w *WindowSpec) *SelectBuilder {
+	b.windowed = true
+	b.windows = append(b.windows, namedWindow{name: name, spec: w})
+	return b
+}
+
+func (b *SelectBuilder) offsetFunc(fn, field string, offset int) string {
+	s := fn + "(" + b.Cond.quoteField(field)
+	if offset > 0 {
+		s += ", " + strconv.Itoa(offset)
+	}
+	return s + ")"
+}
+
+func (b *SelectBuilder) buildWindows() string {
+	if len(b.windows) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(b.windows))
+	for _, w := range b.windows {
+		parts = append(parts, b.Cond.quote(Escape(w.name))+" AS ("+w.spec.build(b.Cond)+")")
+	}
+	return " WINDOW " + strings.Join(parts, ", ")
+}
+
+// checkWindows rejects window functions on servers known to lack them
+func (b *SelectBuilder) checkWindows() error {
+	if !b.windowed {
+		return nil
+	}
+	switch b.Cond.driver.Value() {
+	case driver.MySQL:
+		if driver.VersionBelow(b.Cond.driver, "8.0") {
+			return errWindowUnsupported
+		}
+	case driver.MsSQL:
+		if len(b.windows) > 0 && driver.VersionBelow(b.Cond.driver, "16") {
+			return errNamedWindowUnsupported
+		}
+	}
+	return nil
+}
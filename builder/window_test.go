@@ -0,0 +1,65 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver/mssql"
+	"github.com/zlsgo/zdb/driver/mysql"
+)
+
+func TestSelectWindow(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sb := builder.Query("orders")
+	w := builder.Window().PartitionBy("user_id").OrderBy("created_at DESC")
+	sb.Select(
+		"id",
+		sb.As(sb.RowNumber(w), "rn"),
+		sb.As(sb.Lag("amount", 0, w), "prev"),
+		sb.As(sb.Lead("amount", 2, w), "next"),
+		sb.As(sb.Aggregate("SUM", "amount", builder.Window().PartitionBy("user_id").OrderBy("id").Rows(builder.UnboundedPreceding, builder.CurrentRow)), "total"),
+		sb.As(sb.Aggregate("AVG", "amount", builder.Window().OrderBy("id").Rows(builder.Preceding(2), builder.Following(1))), "avg"),
+		sb.As(sb.Rank(nil), "r"),
+	)
+	sb.Where(sb.Cond.GT("amount", 0))
+
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT "id", ROW_NUMBER() OVER (PARTITION BY "user_id" ORDER BY "created_at" DESC) AS rn, `+
+		`LAG("amount") OVER (PARTITION BY "user_id" ORDER BY "created_at" DESC) AS prev, `+
+		`LEAD("amount", 2) OVER (PARTITION BY "user_id" ORDER BY "created_at" DESC) AS next, `+
+		`SUM("amount") OVER (PARTITION BY "user_id" ORDER BY "id" ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) AS total, `+
+		`AVG("amount") OVER (ORDER BY "id" ROWS BETWEEN 2 PRECEDING AND 1 FOLLOWING) AS avg, `+
+		`RANK() OVER () AS r FROM "orders" WHERE "amount" > ?`, sql)
+	tt.Equal([]interface{}{0}, values)
+}
+
+func TestSelectNamedWindow(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	sb := builder.Query("orders").SetDriver(&mysql.Config{})
+	sb.Window("w", builder.Window().PartitionBy("user_id").OrderBy("id"))
+	sb.Select("id", sb.As(sb.DenseRank(builder.Window("w")), "rn"), sb.As(sb.Aggregate("SUM", "amount", builder.Window("w").Rows(builder.UnboundedPreceding, builder.CurrentRow)), "total"))
+	sb.OrderBy("id")
+
+	sql, _, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal("SELECT `id`, DENSE_RANK() OVER `w` AS rn, SUM(`amount`) OVER (`w` ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) AS total FROM `orders` WINDOW `w` AS (PARTITION BY `user_id` ORDER BY `id`) ORDER BY id", sql)
+
+	old := builder.Query("orders").SetDriver(&mysql.Config{Version: "5.7.44"})
+	old.Select(old.RowNumber(nil))
+	_, _, err = old.Build()
+	tt.EqualTrue(err != nil)
+
+	named := builder.Query("orders").SetDriver(&mssql.Config{Version: "15.0.2000"})
+	named.Window("w", builder.Window().OrderBy("id"))
+	named.Select(named.RowNumber(builder.Window("w")))
+	_, _, err = named.Build()
+	tt.EqualTrue(err != nil)
+
+	named.SetDriver(&mssql.Config{})
+	_, _, err = named.Build()
+	tt.NoError(err)
+}
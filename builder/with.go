@@ -0,0 +1,107 @@
+package builder
+
+import (
+	"strings"
+
+	"github.com/sohaha/zlsgo/zutil"
+	"github.com/zlsgo/zdb/driver"
+)
+
+type (
+	cte struct {
+		builder Builder
+		name    string
+		cols    []string
+	}
+	// withClause holds the common table expressions written before a statement
+	withClause struct {
+		ctes      []cte
+		recursive bool
+	}
+	// nestedBuilder is implemented by the builders that can be compiled inside
+	// another statement, continuing its placeholder numbering
+	nestedBuilder interface {
+		nestedBuild(parent *BuildCond, blend bool, initial []interface{}) (string, []interface{}, error)
+	}
+)
+
+func (w *withClause) add(recursive bool, name string, builder Builder, cols []string) {
+	if recursive {
+		w.recursive = true
+	}
+	w.ctes = append(w.ctes, cte{name: name, cols: cols, builder: builder})
+}
+
+// build returns the WITH prefix, the values of the expressions are appended to values
+// and the errors of the expressions are recorded on cond
+func (w *withClause) build(cond *BuildCond, blend bool, values []interface{}) (string, []interface{}) {
+	if len(w.ctes) == 0 {
+		return "", values
+	}
+
+	driverValue := cond.driver.Value()
+	buf := zutil.GetBuff(uint(64 * len(w.ctes)))
+	defer zutil.PutBuff(buf)
+
+	buf.WriteString("WITH ")
+	if w.recursive && driverValue != driver.MsSQL {
+		buf.WriteString("RECURSIVE ")
+	}
+
+	for i := range w.ctes {
+		c := w.ctes[i]
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(cond.quote(c.name))
+		if len(c.cols) > 0 {
+			buf.WriteString(" (")
+			buf.WriteString(strings.Join(cond.quoteCols(c.cols), ", "))
+			buf.WriteRune(')')
+		}
+		buf.WriteString(" AS (")
+
+		var sql string
+		sql, values = cond.buildNested(c.builder, blend, values)
+		buf.WriteString(sql)
+		buf.WriteRune(')')
+	}
+	buf.WriteRune(' ')
+
+	return buf.String(), values
+}
+
+// buildNested compiles builder with the dialect of c, PostgreSQL and MSSQL
+// placeholders continue after values, the error of builder is recorded on c
+func (c *BuildCond) buildNested(builder Builder, blend bool, values []interface{}) (string, []interface{}) {
+	var (
+		sql  string
+		args []interface{}
+		err  error
+	)
+	if n, ok := builder.(nestedBuilder); ok {
+		sql, args, err = n.nestedBuild(c, blend, values)
+	} else if s, ok := builder.(interface{ String() string }); ok && blend {
+		sql, args = s.String(), values
+	} else if sql, args, err = builder.Build(); err == nil {
+		args = append(values, args...)
+	}
+	if err != nil {
+		if c.err == nil {
+			c.err = err
+		}
+		return "", values
+	}
+	return sql, args
+}
+
+// useDriver switches cond to d until the returned function is called
+func useDriver(cond *BuildCond, d driver.Dialect) func() {
+	old := cond.driver
+	if d != nil {
+		cond.driver = d
+	}
+	return func() {
+		cond.driver = old
+	}
+}
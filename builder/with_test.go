@@ -0,0 +1,177 @@
+package builder_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver/mssql"
+	"github.com/zlsgo/zdb/driver/mysql"
+	"github.com/zlsgo/zdb/driver/postgres"
+)
+
+func TestWith(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	active := builder.Query("user").Select("id")
+	active.Where(active.Cond.EQ("status", 1))
+
+	sb := builder.Query("active").With("active", active)
+	sb.Where(sb.Cond.GT("id", 10))
+
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`WITH "active" AS (SELECT "id" FROM "user" WHERE "status" = ?) SELECT * FROM "active" WHERE "id" > ?`, sql)
+	tt.Equal([]interface{}{1, 10}, values)
+}
+
+func TestWithPostgreSQL(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	active := builder.Query("user").Select("id")
+	active.Where(active.Cond.EQ("status", 1))
+
+	vip := builder.Query("vip").Select("uid")
+	vip.Where(vip.Cond.EQ("level", 3))
+
+	sb := builder.Query("active").SetDriver(&postgres.Config{})
+	sb.With("active", active, "uid")
+	sb.Where(sb.Cond.GT("uid", 10), sb.Cond.In("uid", vip))
+
+	sql, values, err := sb.Build()
+	tt.NoError(err)
+	tt.Equal(`WITH "active" ("uid") AS (SELECT "id" FROM "user" WHERE "status" = $1) SELECT * FROM "active" WHERE "uid" > $2 AND "uid" IN (SELECT "uid" FROM "vip" WHERE "level" = $3)`, sql)
+	tt.Equal([]interface{}{1, 10, 3}, values)
+
+	sql, values, err = sb.Build()
+	tt.NoError(err)
+	tt.Equal(3, len(values))
+}
+
+func TestWithRecursive(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	seed := builder.Select("id", "parent_id").From("category")
+	seed.Where(seed.Cond.EQ("id", 1))
+	next := builder.Select("c.id", "c.parent_id").From("category c").Join("tree t", "c.parent_id = t.id")
+	tree := builder.UnionAll(seed, next)
+
+	for _, v := range []struct {
+		cond   interface{}
+		expect string
+	}{
+		{&postgres.Config{}, `WITH RECURSIVE "tree" AS ((SELECT "id", "parent_id" FROM "category" WHERE "id" = $1) UNION ALL (SELECT "c"."id", "c"."parent_id" FROM "category" c JOIN tree t ON c.parent_id = t.id)) SELECT * FROM "tree" WHERE "id" <> $2`},
+		{&mssql.Config{}, `WITH "tree" AS ((SELECT "id", "parent_id" FROM "category" WHERE "id" = @p1) UNION ALL (SELECT "c"."id", "c"."parent_id" FROM "category" c JOIN tree t ON c.parent_id = t.id)) SELECT * FROM "tree" WHERE "id" <> @p2`},
+	} {
+		sb := builder.Query("tree")
+		switch c := v.cond.(type) {
+		case *postgres.Config:
+			sb.SetDriver(c)
+		case *mssql.Config:
+			sb.SetDriver(c)
+		}
+		sb.WithRecursive("tree", tree)
+		sb.Where(sb.Cond.NE("id", 0))
+
+		sql, values, err := sb.Build()
+		tt.NoError(err)
+		tt.Equal(v.expect, sql)
+		tt.Equal([]interface{}{1, 0}, values)
+	}
+}
+
+func TestWithWrite(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	expired := builder.Query("session").Select("user_id")
+	expired.Where(expired.Cond.LT("expired_at", 100))
+
+	ub := builder.Update("user").SetDriver(&postgres.Config{})
+	ub.With("expired", expired)
+	ub.Set(ub.Assign("online", false))
+	ub.Where(ub.Cond.In("id", builder.Query("expired").Select("user_id")))
+	sql, values, err := ub.Build()
+	tt.NoError(err)
+	tt.Equal(`WITH "expired" AS (SELECT "user_id" FROM "session" WHERE "expired_at" < $1) UPDATE "user" SET "online" = $2 WHERE "id" IN (SELECT "user_id" FROM "expired")`, sql)
+	tt.Equal([]interface{}{100, false}, values)
+
+	db := builder.Delete("user").SetDriver(&mssql.Config{})
+	db.With("expired", expired)
+	db.Where(db.Cond.EQ("online", false), "id IN (SELECT user_id FROM expired)")
+	sql, values, err = db.Build()
+	tt.NoError(err)
+	tt.Equal(`WITH "expired" AS (SELECT "user_id" FROM "session" WHERE "expired_at" < @p1) DELETE FROM "user" WHERE "online" = @p2 AND id IN (SELECT user_id FROM expired)`, sql)
+	tt.Equal([]interface{}{100, false}, values)
+
+	expired = builder.Query("session").Select("user_id").SetDriver(&mysql.Config{})
+	expired.Where(expired.Cond.LT("expired_at", 100))
+	ib := builder.Insert("log").SetDriver(&mysql.Config{})
+	ib.With("expired", expired)
+	ib.Cols("name").Values("x")
+	_, _, err = ib.Build()
+	tt.EqualTrue(err != nil)
+
+	ib = builder.Insert("log").SetDriver(&mysql.Config{})
+	ib.With("expired", expired)
+	ib.Cols("name").Select(builder.Query("expired").Select("user_id").SetDriver(&mysql.Config{}))
+	sql, values, err = ib.Build()
+	tt.NoError(err)
+	tt.Equal("INSERT INTO `log` (`name`) WITH `expired` AS (SELECT `user_id` FROM `session` WHERE `expired_at` < ?) SELECT `user_id` FROM `expired`", sql)
+	tt.Equal([]interface{}{100}, values)
+}
+
+func TestWithNestedError(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	bad := builder.Update("session")
+	bad.Set(bad.Assign("online", false))
+	sb := builder.Query("user").With("expired", bad)
+	_, _, err := sb.Build()
+	tt.EqualTrue(err != nil)
+
+	sub := builder.Query("order").Select("user_id")
+	sub.Where(sub.Cond.EQ("created_at", builder.DateTrunc("week", "now")))
+	sb = builder.Query("user")
+	sb.Where(sb.Cond.In("id", sub))
+	_, _, err = sb.Build()
+	tt.EqualTrue(err != nil)
+
+	ub := builder.Update("user")
+	ub.Set(ub.Assign("vip", true)).Where(ub.Cond.In("id", builder.Build("SELECT id FROM $0", builder.Raw("vip"))))
+	_, _, err = ub.Build()
+	tt.NoError(err)
+}
+
+func TestWithShared(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	active := builder.Query("user").Select("id")
+	active.Where(active.Cond.EQ("status", 1))
+
+	var wg sync.WaitGroup
+	for _, d := range []interface{}{&postgres.Config{}, &mssql.Config{}, &mysql.Config{}, &postgres.Config{}} {
+		wg.Add(1)
+		go func(d interface{}) {
+			defer wg.Done()
+			sb := builder.Query("active")
+			switch c := d.(type) {
+			case *postgres.Config:
+				sb.SetDriver(c)
+			case *mssql.Config:
+				sb.SetDriver(c)
+			case *mysql.Config:
+				sb.SetDriver(c)
+			}
+			sb.With("active", active)
+			_, values, err := sb.Build()
+			tt.NoError(err)
+			tt.Equal([]interface{}{1}, values)
+		}(d)
+	}
+	wg.Wait()
+
+	sql, _, err := active.Build()
+	tt.NoError(err)
+	tt.Equal(`SELECT "id" FROM "user" WHERE "status" = ?`, sql)
+}
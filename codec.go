@@ -0,0 +1,193 @@
+package zdb
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zdb/builder"
+)
+
+type (
+	// Codec converts values of a Go type that does not implement driver.Valuer or sql.Scanner
+	Codec struct {
+		// Encode converts the Go value into a value accepted by the driver
+		Encode func(v interface{}) (interface{}, error)
+		// Decode converts the scanned column value into a value of the registered type
+		Decode func(src interface{}) (interface{}, error)
+	}
+	codecRegistry struct {
+		parent *codecRegistry
+		types  map[reflect.Type]Codec
+		mu     sync.RWMutex
+	}
+)
+
+var globalCodecs = &codecRegistry{}
+
+func init() {
+	builder.DefaultEncoder = globalCodecs.encode
+}
+
+// RegisterCodec registers the codec for the type of typ on all databases,
+// struct types should be registered before the models using them are parsed
+func RegisterCodec(typ interface{}, codec Codec) {
+	globalCodecs.register(reflect.TypeOf(typ), codec)
+}
+
+// RegisterCodec registers the codec for the type of typ on this database only,
+// it takes precedence over the global codec of the same type.
+// Models are parsed once for all databases, so a struct type is only written as a
+// single column when its codec is registered with the package level RegisterCodec
+func (e *DB) RegisterCodec(typ interface{}, codec Codec) {
+	if e.codecs == nil {
+		e.codecs = newCodecRegistry()
+	}
+	e.codecs.register(reflect.TypeOf(typ), codec)
+}
+
+func newCodecRegistry() *codecRegistry {
+	return &codecRegistry{parent: globalCodecs}
+}
+
+func (c *codecRegistry) register(typ reflect.Type, codec Codec) {
+	if typ == nil {
+		return
+	}
+	c.mu.Lock()
+	if c.types == nil {
+		c.types = make(map[reflect.Type]Codec)
+	}
+	c.types[typ] = codec
+	c.mu.Unlock()
+}
+
+func (c *codecRegistry) lookup(typ reflect.Type) (Codec, bool) {
+	if c == nil {
+		c = globalCodecs
+	}
+	for ; c != nil; c = c.parent {
+		c.mu.RLock()
+		codec, ok := c.types[typ]
+		c.mu.RUnlock()
+		if ok {
+			return codec, true
+		}
+	}
+	return Codec{}, false
+}
+
+func (c *codecRegistry) empty() bool {
+	if c == nil {
+		c = globalCodecs
+	}
+	for ; c != nil; c = c.parent {
+		c.mu.RLock()
+		n := len(c.types)
+		c.mu.RUnlock()
+		if n > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// has reports whether typ or the type it points to has a codec
+func (c *codecRegistry) has(typ reflect.Type) bool {
+	if _, ok := c.lookup(typ); ok {
+		return true
+	}
+	if typ.Kind() == reflect.Ptr {
+		_, ok := c.lookup(typ.Elem())
+		return ok
+	}
+	return false
+}
+
+// encode converts v with its codec, nil pointers of a registered type are written as NULL
+func (c *codecRegistry) encode(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	typ := reflect.TypeOf(v)
+	codec, ok := c.lookup(typ)
+	if !ok && typ.Kind() == reflect.Ptr {
+		if codec, ok = c.lookup(typ.Elem()); ok {
+			rv := reflect.ValueOf(v)
+			if rv.IsNil() {
+				return nil, nil
+			}
+			v = rv.Elem().Interface()
+		}
+	}
+	if !ok || codec.Encode == nil {
+		return v, nil
+	}
+	return codec.Encode(v)
+}
+
+func (c *codecRegistry) encodeArgs(args []interface{}) ([]interface{}, error) {
+	if c.empty() {
+		return args, nil
+	}
+	encoded := make([]interface{}, len(args))
+	for i := range args {
+		v, err := c.encode(args[i])
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = v
+	}
+	return encoded, nil
+}
+
+// decode sets field from the scanned value when its type has a codec
+func (c *codecRegistry) decode(src interface{}, field reflect.Value) (bool, error) {
+	typ := field.Type()
+	codec, ok := c.lookup(typ)
+	ptr := false
+	if !ok && typ.Kind() == reflect.Ptr {
+		codec, ok = c.lookup(typ.Elem())
+		ptr = true
+	}
+	if !ok || codec.Decode == nil {
+		return false, nil
+	}
+
+	v, err := codec.Decode(src)
+	if err != nil {
+		return true, err
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		field.Set(reflect.Zero(typ))
+		return true, nil
+	}
+	if ptr {
+		if rv.Type() != typ {
+			p := reflect.New(typ.Elem())
+			p.Elem().Set(rv)
+			rv = p
+		}
+	}
+	field.Set(rv)
+	return true, nil
+}
+
+// convOption returns the conversion options that apply the codecs to the target types
+func (c *codecRegistry) convOption(conver *ztype.Conver) {
+	convOption(conver)
+	if c.empty() {
+		return
+	}
+	hook := conver.ConvHook
+	conver.ConvHook = func(name string, i reflect.Value, o reflect.Type) (reflect.Value, bool) {
+		if _, ok := c.lookup(o); ok && i.IsValid() && i.Kind() != reflect.Map {
+			field := reflect.New(o).Elem()
+			if ok, err := c.decode(i.Interface(), field); ok && err == nil {
+				return field, false
+			}
+		}
+		return hook(name, i, o)
+	}
+}
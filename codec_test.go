@@ -0,0 +1,150 @@
+package zdb_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zdb"
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver"
+	"github.com/zlsgo/zdb/driver/sqlite3"
+	"github.com/zlsgo/zdb/testdata"
+)
+
+type codecMoney struct {
+	Currency string
+	Cents    int64
+}
+
+type codecSecret string
+
+type codecShift int
+
+type codecRow struct {
+	ID     int         `zdb:"id"`
+	Price  codecMoney  `zdb:"price"`
+	Refund *codecMoney `zdb:"refund"`
+	Secret codecSecret `zdb:"secret"`
+}
+
+func init() {
+	zdb.RegisterCodec(codecMoney{}, zdb.Codec{
+		Encode: func(v interface{}) (interface{}, error) {
+			m := v.(codecMoney)
+			return fmt.Sprintf("%s:%d", m.Currency, m.Cents), nil
+		},
+		Decode: func(src interface{}) (interface{}, error) {
+			s := strings.SplitN(ztype.ToString(src), ":", 2)
+			if len(s) != 2 {
+				return nil, errors.New("invalid money")
+			}
+			return codecMoney{Currency: s[0], Cents: ztype.ToInt64(s[1])}, nil
+		},
+	})
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+func TestCodec(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("codec")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	db.RegisterCodec(codecSecret(""), zdb.Codec{
+		Encode: func(v interface{}) (interface{}, error) {
+			return reverse(string(v.(codecSecret))), nil
+		},
+		Decode: func(src interface{}) (interface{}, error) {
+			return codecSecret(reverse(ztype.ToString(src))), nil
+		},
+	})
+
+	_, _ = db.Exec(`DROP TABLE codec_row`)
+	_, err = db.Exec(`CREATE TABLE codec_row (id INTEGER PRIMARY KEY, price TEXT, refund TEXT, secret TEXT)`)
+	tt.NoError(err)
+
+	id, err := db.Insert("codec_row", &codecRow{
+		Price:  codecMoney{Currency: "USD", Cents: 100},
+		Secret: "abc",
+	})
+	tt.NoError(err)
+	_, err = db.Insert("codec_row", map[string]interface{}{
+		"price":  codecMoney{Currency: "CNY", Cents: 5},
+		"refund": &codecMoney{Currency: "CNY", Cents: 1},
+		"secret": codecSecret("xyz"),
+	})
+	tt.NoError(err)
+
+	m, err := db.FindOne("codec_row", func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal("USD:100", m.Get("price").String())
+	tt.Equal(nil, m["refund"])
+	tt.Equal("cba", m.Get("secret").String())
+
+	rows, err := zdb.Find[codecRow](db, "codec_row", func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.EQ("secret", codecSecret("xyz")))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(1, len(rows))
+	tt.Equal(codecMoney{Currency: "CNY", Cents: 5}, rows[0].Price)
+	tt.Equal(codecMoney{Currency: "CNY", Cents: 1}, *rows[0].Refund)
+	tt.Equal(codecSecret("xyz"), rows[0].Secret)
+
+	var prices []map[string]codecMoney
+	err = db.QueryTo(&prices, `SELECT price FROM codec_row ORDER BY id`)
+	tt.NoError(err)
+	tt.Equal(codecMoney{Currency: "USD", Cents: 100}, prices[0]["price"])
+
+	other, err := zdb.New(dbConf)
+	tt.NoError(err)
+	row, err := zdb.FindOne[codecRow](other, "codec_row", func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(codecSecret("cba"), row.Secret)
+	tt.EqualTrue(row.Refund == nil)
+
+	db.RegisterCodec(codecShift(0), zdb.Codec{
+		Encode: func(v interface{}) (interface{}, error) {
+			return v.(codecShift) + 100, nil
+		},
+	})
+	_, err = db.Insert("codec_row", map[string]interface{}{"id": codecShift(1)})
+	tt.NoError(err)
+	m, err = db.FindOne("codec_row", func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.EQ("id", 101))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(101, m.Get("id").Int())
+
+	sb := builder.Query("codec_row")
+	sb.Where(sb.Cond.EQ("price", codecMoney{Currency: "USD", Cents: 100}))
+	inline, err := builder.Inline(sb, driver.SQLite)
+	tt.NoError(err)
+	tt.Equal(`SELECT * FROM "codec_row" WHERE "price" = 'USD:100'`, inline)
+}
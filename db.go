@@ -2,9 +2,11 @@ package zdb
 
 import (
 	"database/sql"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/sohaha/zlsgo/zarray"
 	"github.com/zlsgo/zdb/builder"
 	"github.com/zlsgo/zdb/driver"
 )
@@ -16,11 +18,20 @@ type (
 		dsn    string
 	}
 	DB struct {
-		driver  driver.Dialect
-		session *Session
-		pools   []*Config
-		Debug   bool
-		idKey   string
+		driver      driver.Dialect
+		session     *Session
+		pools       []*Config
+		writeOnly   []string
+		writeOmit   []string
+		scanOptions ScanOptions
+		codecs      *codecRegistry
+		columns     map[string][]string
+		guard       GuardOptions
+		unsafe      string
+		idKey       string
+		Debug       bool
+		strict      bool
+		migrating   bool
 	}
 	JsonTime time.Time
 )
@@ -29,7 +40,8 @@ var engines sync.Map
 
 func New(cfg driver.IfeConfig, alias ...string) (e *DB, err error) {
 	e = &DB{
-		idKey: builder.IDKey,
+		idKey:  builder.IDKey,
+		codecs: newCodecRegistry(),
 	}
 	err = e.add(cfg)
 	if len(alias) > 0 {
@@ -40,7 +52,8 @@ func New(cfg driver.IfeConfig, alias ...string) (e *DB, err error) {
 
 func NewCluster(cfgs []driver.IfeConfig, alias ...string) (e *DB, err error) {
 	e = &DB{
-		idKey: builder.IDKey,
+		idKey:  builder.IDKey,
+		codecs: newCodecRegistry(),
 	}
 	for i := range cfgs {
 		err = e.add(cfgs[i])
@@ -68,6 +81,7 @@ func MustInstance(alias string) (*DB, error) {
 	return &DB{
 		driver: builder.DefaultDriver,
 		idKey:  builder.IDKey,
+		codecs: newCodecRegistry(),
 	}, ErrDBNotExist
 }
 
@@ -81,8 +95,14 @@ func (e *DB) add(c driver.IfeConfig) (err error) {
 	}
 
 	cfg.driver = e.toDialect(c)
+	e.scanOptions.TinyIntAsBool = tinyIntAsBool(cfg.driver)
 
 	if err = cfg.db.Ping(); err == nil {
+		if d, ok := c.(driver.VersionDetector); ok {
+			if verr := d.DetectVersion(cfg.db); verr != nil {
+				log.Warn("detect server version:", verr)
+			}
+		}
 		e.pools = append(e.pools, cfg)
 	}
 	return err
@@ -123,6 +143,58 @@ func (e *DB) SetIDKey(key string) {
 	e.idKey = key
 }
 
+// Select returns a DB that only writes the given columns in Insert/Update,
+// selected struct fields are written even when they are empty.
+// Chained calls add to the columns, like Omit
+func (e *DB) Select(cols ...string) *DB {
+	nEngine := *e
+	nEngine.writeOnly = append(append([]string{}, e.writeOnly...), cols...)
+	return &nEngine
+}
+
+// Omit returns a DB that skips the given columns in Insert/Update,
+// chained calls add to the columns
+func (e *DB) Omit(cols ...string) *DB {
+	nEngine := *e
+	nEngine.writeOmit = append(append([]string{}, e.writeOmit...), cols...)
+	return &nEngine
+}
+
+// SetStrict returns a DB that validates the tables and columns given to its builders,
+// names are escaped for the dialect and expressions must be wrapped in builder.Raw,
+// see builder.BuildCond.SetStrict
+func (e *DB) SetStrict(strict bool) *DB {
+	nEngine := *e
+	nEngine.strict = strict
+	return &nEngine
+}
+
+// AllowColumns returns a DB that limits the columns Insert/Update write to table to cols,
+// writing any other column returns an error instead of passing user input through
+func (e *DB) AllowColumns(table string, cols ...string) *DB {
+	nEngine := *e
+	nEngine.columns = make(map[string][]string, len(e.columns)+1)
+	for t, c := range e.columns {
+		nEngine.columns[t] = c
+	}
+	nEngine.columns[table] = append([]string{}, cols...)
+	return &nEngine
+}
+
+// checkColumns returns an error when a column is not allowed by AllowColumns
+func (e *DB) checkColumns(table string, cols []string) error {
+	allowed, ok := e.columns[table]
+	if !ok {
+		return nil
+	}
+	for _, col := range cols {
+		if !zarray.Contains(allowed, col) {
+			return fmt.Errorf("column %s of table %s is not writable", col, table)
+		}
+	}
+	return nil
+}
+
 func (e *DB) withSession(s *Session) *DB {
 	nEngine := *e
 	nEngine.session = s
@@ -0,0 +1,183 @@
+package zdb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zlsgo/zdb/driver"
+)
+
+type (
+	// ScanOptions controls how ScanToMap decodes the column values
+	ScanOptions struct {
+		// Raw keeps the driver values as they are, []byte is returned as string
+		Raw bool
+		// TinyIntAsBool decodes TINYINT(1) columns as bool, the MySQL driver does not
+		// report the display width, so there every TINYINT column is decoded as bool.
+		// It is on by default for MySQL, where TINYINT(1) is the BOOL type
+		TinyIntAsBool bool
+	}
+	columnKind  uint8
+	columnTyper interface {
+		ColumnTypes() ([]*sql.ColumnType, error)
+	}
+)
+
+const (
+	columnDefault columnKind = iota
+	columnInt
+	columnUint
+	columnFloat
+	columnDecimal
+	columnBool
+	columnJSON
+	columnTime
+)
+
+// DefaultScanOptions is used by the package level ScanToMap and Scan
+var DefaultScanOptions = ScanOptions{}
+
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02",
+}
+
+// ScanOptions returns a DB that decodes the query results with the options set by fn
+func (e *DB) ScanOptions(fn func(o *ScanOptions)) *DB {
+	nEngine := *e
+	fn(&nEngine.scanOptions)
+	return &nEngine
+}
+
+// tinyIntAsBool returns the default of ScanOptions.TinyIntAsBool for the dialect
+func tinyIntAsBool(d driver.Dialect) bool {
+	return d != nil && d.Value() == driver.MySQL
+}
+
+func resolveColumnKinds(rows IfeRows, length int, opt ScanOptions) []columnKind {
+	if opt.Raw {
+		return nil
+	}
+	ct, ok := rows.(columnTyper)
+	if !ok {
+		return nil
+	}
+	types, err := ct.ColumnTypes()
+	if err != nil || len(types) != length {
+		return nil
+	}
+
+	kinds := make([]columnKind, length)
+	for i := range types {
+		length, _ := types[i].Length()
+		kinds[i] = columnKindOf(types[i].DatabaseTypeName(), length, opt)
+	}
+	return kinds
+}
+
+// columnKindOf returns the kind of the database type name, length is the
+// width of the column and 0 when the driver does not report it
+func columnKindOf(name string, length int64, opt ScanOptions) columnKind {
+	name = strings.ToUpper(name)
+	if i := strings.IndexByte(name, '('); i > 0 {
+		if strings.HasPrefix(name, "NULLABLE(") || strings.HasPrefix(name, "LOWCARDINALITY(") {
+			name = strings.TrimSuffix(name[i+1:], ")")
+			return columnKindOf(name, length, opt)
+		}
+		if n, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(name[i+1:], ")")), 10, 64); err == nil {
+			length = n
+		}
+		name = strings.TrimSpace(name[:i])
+	}
+
+	switch name {
+	case "TINYINT":
+		if opt.TinyIntAsBool && length <= 1 {
+			return columnBool
+		}
+		return columnInt
+	case "BIT":
+		if length == 1 {
+			return columnBool
+		}
+		return columnDefault
+	case "INT", "INTEGER", "SMALLINT", "MEDIUMINT", "BIGINT", "INT2", "INT4", "INT8",
+		"INT16", "INT32", "INT64", "SERIAL", "BIGSERIAL", "YEAR":
+		return columnInt
+	case "UNSIGNED TINYINT", "UNSIGNED SMALLINT", "UNSIGNED MEDIUMINT", "UNSIGNED INT", "UNSIGNED BIGINT",
+		"UINT8", "UINT16", "UINT32", "UINT64":
+		return columnUint
+	case "FLOAT", "DOUBLE", "REAL", "FLOAT4", "FLOAT8", "FLOAT32", "FLOAT64":
+		return columnFloat
+	case "DECIMAL", "NUMERIC", "NEWDECIMAL", "MONEY", "SMALLMONEY":
+		return columnDecimal
+	case "BOOL", "BOOLEAN":
+		return columnBool
+	case "JSON", "JSONB":
+		return columnJSON
+	case "DATE", "DATETIME", "DATETIME2", "DATETIME64", "SMALLDATETIME", "DATETIMEOFFSET", "TIMESTAMP", "TIMESTAMPTZ":
+		return columnTime
+	}
+	return columnDefault
+}
+
+// decodeColumn converts the driver value by the database type of the column,
+// values that cannot be parsed are returned as string
+func decodeColumn(kind columnKind, val interface{}) interface{} {
+	var s string
+	switch v := val.(type) {
+	case nil:
+		return nil
+	case []byte:
+		if kind == columnBool && len(v) == 1 && v[0] < '0' {
+			return v[0] != 0
+		}
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		if kind == columnBool {
+			return v != 0
+		}
+		return v
+	default:
+		return v
+	}
+
+	switch kind {
+	case columnInt:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	case columnUint:
+		if i, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return i
+		}
+	case columnFloat:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case columnBool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case columnJSON:
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err == nil {
+			return v
+		}
+	case columnTime:
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				return t
+			}
+		}
+	}
+	return s
+}
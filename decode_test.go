@@ -0,0 +1,48 @@
+package zdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zdb/driver/mysql"
+	"github.com/zlsgo/zdb/driver/sqlite3"
+)
+
+func TestDecodeColumn(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	tt.EqualTrue(tinyIntAsBool(&mysql.Config{}))
+	tt.EqualTrue(!tinyIntAsBool(&sqlite3.Config{}))
+	tt.EqualTrue(!tinyIntAsBool(nil))
+
+	tt.Equal(columnInt, columnKindOf("bigint", 0, ScanOptions{}))
+	tt.Equal(columnUint, columnKindOf("UNSIGNED BIGINT", 0, ScanOptions{}))
+	tt.Equal(columnInt, columnKindOf("TINYINT", 0, ScanOptions{}))
+	tt.Equal(columnBool, columnKindOf("TINYINT", 0, ScanOptions{TinyIntAsBool: true}))
+	tt.Equal(columnBool, columnKindOf("TINYINT(1)", 0, ScanOptions{TinyIntAsBool: true}))
+	tt.Equal(columnInt, columnKindOf("TINYINT(4)", 0, ScanOptions{TinyIntAsBool: true}))
+	tt.Equal(columnInt, columnKindOf("TINYINT", 4, ScanOptions{TinyIntAsBool: true}))
+	tt.Equal(columnBool, columnKindOf("BIT(1)", 0, ScanOptions{}))
+	tt.Equal(columnBool, columnKindOf("BIT", 1, ScanOptions{}))
+	tt.Equal(columnDefault, columnKindOf("BIT(8)", 0, ScanOptions{}))
+	tt.Equal(columnDefault, columnKindOf("BIT", 0, ScanOptions{}))
+	tt.Equal(columnDecimal, columnKindOf("Decimal(10, 2)", 0, ScanOptions{}))
+	tt.Equal(columnUint, columnKindOf("Nullable(UInt64)", 0, ScanOptions{}))
+	tt.Equal(columnDefault, columnKindOf("VARCHAR", 0, ScanOptions{}))
+
+	tt.Equal(int64(12), decodeColumn(columnInt, []byte("12")))
+	tt.Equal(uint64(18446744073709551615), decodeColumn(columnUint, []byte("18446744073709551615")))
+	tt.Equal(1.5, decodeColumn(columnFloat, []byte("1.5")))
+	tt.Equal("12.30", decodeColumn(columnDecimal, []byte("12.30")))
+	tt.Equal(true, decodeColumn(columnBool, []byte("1")))
+	tt.Equal(true, decodeColumn(columnBool, []byte{1}))
+	tt.Equal(false, decodeColumn(columnBool, int64(0)))
+	tt.Equal(map[string]interface{}{"a": 1.0}, decodeColumn(columnJSON, []byte(`{"a":1}`)))
+	tt.Equal("abc", decodeColumn(columnInt, []byte("abc")))
+	tt.Equal(nil, decodeColumn(columnInt, nil))
+
+	date := decodeColumn(columnTime, []byte("2021-11-11 15:00:01"))
+	tt.Equal(time.Date(2021, 11, 11, 15, 0, 1, 0, time.Local), date)
+	tt.Equal("0000-00-00 00:00:00", decodeColumn(columnTime, []byte("0000-00-00 00:00:00")))
+}
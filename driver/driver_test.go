@@ -58,4 +58,20 @@ func TestDorisQuote(t *testing.T) {
 
 	tt.Equal("`name`", driver.Doris.Quote("name"))
 	tt.Equal("`table`.`column`", driver.Doris.Quote("table.column"))
+	tt.Equal("`1`", driver.Doris.Quote("1"))
+}
+
+type versionConfig string
+
+func (v versionConfig) ServerVersion() string { return string(v) }
+
+func TestVersionBelow(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	tt.EqualTrue(driver.VersionBelow(versionConfig("5.7.44-log"), "8.0"))
+	tt.EqualTrue(driver.VersionBelow(versionConfig("8.0.30"), "8.0.31"))
+	tt.EqualTrue(!driver.VersionBelow(versionConfig("8.0.31"), "8.0.31"))
+	tt.EqualTrue(!driver.VersionBelow(versionConfig("10.11.2-MariaDB"), "8.0"))
+	tt.EqualTrue(!driver.VersionBelow(versionConfig(""), "8.0"))
+	tt.EqualTrue(!driver.VersionBelow(driver.MySQL, "8.0"))
 }
@@ -11,8 +11,9 @@ import (
 )
 
 var (
-	_ driver.IfeConfig = &Config{}
-	_ driver.Dialect   = &Config{}
+	_ driver.IfeConfig       = &Config{}
+	_ driver.Dialect         = &Config{}
+	_ driver.VersionDetector = &Config{}
 )
 
 // Config database configuration
@@ -24,6 +25,10 @@ type Config struct {
 	Password   string
 	DBName     string
 	Parameters string
+	// Version is the SQL Server version such as 16.0.1000 (SQL Server 2022), builders reject
+	// features it lacks (the WINDOW clause needs 16). It is read from SERVERPROPERTY('ProductVersion')
+	// when the connection is opened if it is empty
+	Version string
 	driver.Typ
 	Port int
 }
@@ -71,6 +76,19 @@ func (c *Config) GetDriver() string {
 	return "mssql"
 }
 
+// ServerVersion returns the configured or detected server version
+func (c *Config) ServerVersion() string {
+	return c.Version
+}
+
+// DetectVersion reads the server version when Version is not configured
+func (c *Config) DetectVersion(db *sql.DB) (err error) {
+	if c.Version == "" {
+		c.Version, err = driver.ReadVersion(db, "SELECT CAST(SERVERPROPERTY('ProductVersion') AS NVARCHAR(128))")
+	}
+	return
+}
+
 func (c *Config) Value() driver.Typ {
 	return driver.MsSQL
 }
@@ -16,8 +16,9 @@ import (
 )
 
 var (
-	_ driver.IfeConfig = &Config{}
-	_ driver.Dialect   = &Config{}
+	_ driver.IfeConfig       = &Config{}
+	_ driver.Dialect         = &Config{}
+	_ driver.VersionDetector = &Config{}
 )
 
 // Config databaseName configuration
@@ -31,6 +32,9 @@ type Config struct {
 	Charset    string
 	Zone       string
 	Parameters string
+	// Version is the server version such as 5.7.44, builders reject features it lacks.
+	// It is read with SELECT VERSION() when the connection is opened if it is empty
+	Version string
 	driver.Typ
 	Port int
 }
@@ -91,6 +95,19 @@ func (c *Config) GetDriver() string {
 	return "mysql"
 }
 
+// ServerVersion returns the configured or detected server version
+func (c *Config) ServerVersion() string {
+	return c.Version
+}
+
+// DetectVersion reads the server version when Version is not configured
+func (c *Config) DetectVersion(db *sql.DB) (err error) {
+	if c.Version == "" {
+		c.Version, err = driver.ReadVersion(db, "SELECT VERSION()")
+	}
+	return
+}
+
 func (c *Config) Value() driver.Typ {
 	return driver.MySQL
 }
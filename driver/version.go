@@ -0,0 +1,80 @@
+package driver
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Versioner is implemented by the configs that know the version of the server,
+	// builders use it to reject statements the server cannot run
+	Versioner interface {
+		ServerVersion() string
+	}
+	// VersionDetector is implemented by the configs that read the version from the
+	// server when it is not configured, zdb calls it once the connection is opened
+	VersionDetector interface {
+		DetectVersion(db *sql.DB) error
+	}
+)
+
+// ReadVersion returns the first column of the row of query, such as SELECT VERSION()
+func ReadVersion(db *sql.DB, query string) (string, error) {
+	var version string
+	if err := db.QueryRow(query).Scan(&version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// VersionBelow reports whether the server version of d is known and lower than version,
+// an unknown version (not configured and not detected) is treated as new enough
+func VersionBelow(d interface{}, version string) bool {
+	v, ok := d.(Versioner)
+	if !ok {
+		return false
+	}
+	current := v.ServerVersion()
+	if current == "" {
+		return false
+	}
+	return compareVersion(current, version) < 0
+}
+
+func compareVersion(a, b string) int {
+	as, bs := versionParts(a), versionParts(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts parses the leading numbers of versions such as 8.0.31-log
+func versionParts(v string) []int {
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	nums := make([]int, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			break
+		}
+		nums = append(nums, n)
+	}
+	return nums
+}
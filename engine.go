@@ -12,23 +12,48 @@ func (e *DB) GetDriver() driver.Dialect {
 }
 
 func (e *DB) Exec(sql string, values ...interface{}) (sql.Result, error) {
+	values, err := e.codecs.encodeArgs(values)
+	if err != nil {
+		return nil, err
+	}
+	return e.exec(sql, values)
+}
+
+// exec runs the statement with values that are already encoded,
+// the builders of the DB encode their values when they are compiled
+func (e *DB) exec(query string, values []interface{}) (sql.Result, error) {
 	db, err := e.getSession(nil, true)
 	if err != nil {
 		return nil, err
 	}
 	defer e.putSessionPool(db, false)
 
-	return db.execContext(db.ctx, sql, values...)
+	if err = e.checkStatement(query, values); err != nil {
+		return nil, err
+	}
+	return db.execContext(db.ctx, query, values...)
 }
 
 func (e *DB) Query(sql string, values ...interface{}) (*sql.Rows, error) {
+	values, err := e.codecs.encodeArgs(values)
+	if err != nil {
+		return nil, err
+	}
+	return e.query(sql, values)
+}
+
+// query runs the query with values that are already encoded, see exec
+func (e *DB) query(query string, values []interface{}) (*sql.Rows, error) {
 	db, err := e.getSession(nil, false)
 	if err != nil {
 		return nil, err
 	}
 	defer e.putSessionPool(db, false)
 
-	return db.queryContext(db.ctx, sql, values...)
+	if err = e.checkStatement(query, values); err != nil {
+		return nil, err
+	}
+	return db.queryContext(db.ctx, query, values...)
 }
 
 func (e *DB) Transaction(run DBCallback, ctx ...context.Context) error {
@@ -8,6 +8,8 @@ const (
 	ErrNotFound
 	ErrModuleAlreadyExists
 	ErrNotMigration
+	// ErrTooManyRows the query returns more rows than GuardOptions.MaxRows
+	ErrTooManyRows
 	errCount
 )
 
@@ -16,6 +18,7 @@ var errDescriptions = [...]string{
 	ErrNotFound:            "找不到记录",
 	ErrModuleAlreadyExists: "模型已存在",
 	ErrNotMigration:        "不支持表迁移",
+	ErrTooManyRows:         "查询行数超出限制",
 }
 
 var _ = [1]int{}[len(errDescriptions)-int(errCount)]
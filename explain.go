@@ -0,0 +1,379 @@
+package zdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver"
+)
+
+type (
+	// ExplainOptions configures DB.Explain
+	ExplainOptions struct {
+		// Analyze executes the statement to report the actual rows, it is only supported by PostgreSQL.
+		// As the statement really runs, only SelectBuilder and UnionBuilder are accepted
+		Analyze bool
+	}
+	// Plan is a node of a query plan normalized across the dialects,
+	// the numbers the dialect does not report are zero
+	Plan struct {
+		Type       string  `json:"type"`
+		Table      string  `json:"table,omitempty"`
+		Index      string  `json:"index,omitempty"`
+		Detail     string  `json:"detail,omitempty"`
+		Children   []*Plan `json:"children,omitempty"`
+		Rows       float64 `json:"rows"`
+		ActualRows float64 `json:"actual_rows,omitempty"`
+		Cost       float64 `json:"cost"`
+		FullScan   bool    `json:"full_scan"`
+	}
+)
+
+var sqlitePlanDetail = regexp.MustCompile(`^(SCAN|SEARCH)(?: TABLE)? (\S+)(?: AS \S+)?(?: USING (?:(?:AUTOMATIC )?(?:PARTIAL )?COVERING )?INDEX (\S+)| USING (?:INTEGER )?PRIMARY KEY)?`)
+
+// Explain returns the plan of the statement b compiled for the dialect of the DB, it runs EXPLAIN FORMAT=JSON
+// on MySQL, EXPLAIN (FORMAT JSON) on PostgreSQL, EXPLAIN QUERY PLAN on SQLite and EXPLAIN indexes = 1 on ClickHouse
+func (e *DB) Explain(b builder.Builder, opts ...ExplainOptions) (*Plan, error) {
+	var opt ExplainOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	if opt.Analyze {
+		switch b.(type) {
+		case *builder.SelectBuilder, *builder.UnionBuilder:
+		default:
+			return nil, fmt.Errorf("explain error: Analyze would run %T, only SELECT is accepted", b)
+		}
+	}
+
+	d := e.driver.Value()
+	sql, values, err := builder.Render(b, d)
+	if err != nil {
+		return nil, err
+	}
+
+	if opt.Analyze && d != driver.PostgreSQL {
+		return nil, errors.New("explain error: Analyze is only supported by PostgreSQL")
+	}
+
+	var prefix string
+	switch d {
+	case driver.MySQL:
+		prefix = "EXPLAIN FORMAT=JSON "
+	case driver.PostgreSQL:
+		prefix = "EXPLAIN (FORMAT JSON) "
+		if opt.Analyze {
+			prefix = "EXPLAIN (ANALYZE, FORMAT JSON) "
+		}
+	case driver.SQLite:
+		prefix = "EXPLAIN QUERY PLAN "
+	case driver.ClickHouse:
+		prefix = "EXPLAIN indexes = 1 "
+	default:
+		return nil, errors.New("explain error: EXPLAIN is not supported by " + d.String())
+	}
+
+	rows, err := e.queryToMaps(prefix+sql, values)
+	if err != nil {
+		return nil, err
+	}
+
+	switch d {
+	case driver.MySQL:
+		return explainMySQL(firstColumn(rows))
+	case driver.PostgreSQL:
+		return explainPostgres(firstColumn(rows))
+	case driver.SQLite:
+		return explainSQLite(rows), nil
+	}
+	lines := make([]string, 0, len(rows))
+	for i := range rows {
+		lines = append(lines, firstColumn(rows[i:i+1]))
+	}
+	return explainClickHouse(lines), nil
+}
+
+// FullScans returns the nodes of the plan that scan a whole table
+func (p *Plan) FullScans() []*Plan {
+	var scans []*Plan
+	p.walk(func(n *Plan) {
+		if n.FullScan {
+			scans = append(scans, n)
+		}
+	})
+	return scans
+}
+
+// String returns the plan as an indented tree, full table scans are marked with [FULL SCAN]
+func (p *Plan) String() string {
+	var buf strings.Builder
+	p.write(&buf, 0)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func (p *Plan) write(buf *strings.Builder, depth int) {
+	buf.WriteString(strings.Repeat("  ", depth))
+	buf.WriteString(p.Type)
+	if p.Table != "" {
+		buf.WriteString(" on " + p.Table)
+	}
+	if p.Index != "" {
+		buf.WriteString(" using " + p.Index)
+	}
+	if p.Rows > 0 {
+		buf.WriteString(fmt.Sprintf(" rows=%g", p.Rows))
+	}
+	if p.ActualRows > 0 {
+		buf.WriteString(fmt.Sprintf(" actual_rows=%g", p.ActualRows))
+	}
+	if p.Cost > 0 {
+		buf.WriteString(fmt.Sprintf(" cost=%g", p.Cost))
+	}
+	if p.FullScan {
+		buf.WriteString(" [FULL SCAN]")
+	}
+	buf.WriteString("\n")
+	for _, c := range p.Children {
+		c.write(buf, depth+1)
+	}
+}
+
+func (p *Plan) walk(fn func(*Plan)) {
+	fn(p)
+	for _, c := range p.Children {
+		c.walk(fn)
+	}
+}
+
+// firstColumn returns the text of the only column of the first row
+func firstColumn(rows ztype.Maps) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	for _, v := range rows[0] {
+		return ztype.ToString(v)
+	}
+	return ""
+}
+
+func explainMySQL(data string) (*Plan, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, fmt.Errorf("explain error: %v", err)
+	}
+	root := &Plan{}
+	root.addMySQL("", doc)
+	if len(root.Children) == 1 {
+		return root.Children[0], nil
+	}
+	root.Type = "QUERY"
+	return root, nil
+}
+
+// mysqlOperations are the objects of a MySQL plan that become nodes of their own
+var mysqlOperations = map[string]bool{
+	"ordering_operation": true, "grouping_operation": true, "duplicates_removal": true,
+	"windowing": true, "union_result": true, "materialized_from_subquery": true,
+}
+
+// addMySQL adds the nodes found in the value of key of a MySQL JSON plan to p
+func (p *Plan) addMySQL(key string, value interface{}) {
+	switch v := value.(type) {
+	case []interface{}:
+		for i := range v {
+			p.addMySQL("", v[i])
+		}
+		return
+	case map[string]interface{}:
+		node := p
+		switch {
+		case key == "query_block":
+			node = &Plan{Type: "QUERY BLOCK", Cost: mysqlCost(v, "query_cost")}
+		case key == "table":
+			access := ztype.ToString(v["access_type"])
+			node = &Plan{
+				Type:     access,
+				Table:    ztype.ToString(v["table_name"]),
+				Index:    ztype.ToString(v["key"]),
+				Rows:     ztype.ToFloat64(v["rows_examined_per_scan"]),
+				Cost:     mysqlCost(v, "prefix_cost"),
+				FullScan: access == "ALL",
+			}
+			if node.Type == "" {
+				node.Type = "TABLE"
+			}
+		case mysqlOperations[key]:
+			node = &Plan{Type: strings.ToUpper(strings.ReplaceAll(key, "_", " "))}
+		}
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if k != "cost_info" {
+				node.addMySQL(k, v[k])
+			}
+		}
+		if node != p {
+			p.Children = append(p.Children, node)
+		}
+	}
+}
+
+func mysqlCost(m map[string]interface{}, key string) float64 {
+	info, _ := m["cost_info"].(map[string]interface{})
+	return ztype.ToFloat64(info[key])
+}
+
+func explainPostgres(data string) (*Plan, error) {
+	var doc []struct {
+		Plan map[string]interface{} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, fmt.Errorf("explain error: %v", err)
+	}
+	if len(doc) == 0 || doc[0].Plan == nil {
+		return nil, errors.New("explain error: empty plan")
+	}
+	return postgresPlan(doc[0].Plan), nil
+}
+
+func postgresPlan(m map[string]interface{}) *Plan {
+	node := &Plan{
+		Type:       ztype.ToString(m["Node Type"]),
+		Table:      ztype.ToString(m["Relation Name"]),
+		Index:      ztype.ToString(m["Index Name"]),
+		Rows:       ztype.ToFloat64(m["Plan Rows"]),
+		ActualRows: ztype.ToFloat64(m["Actual Rows"]),
+		Cost:       ztype.ToFloat64(m["Total Cost"]),
+	}
+	node.FullScan = node.Type == "Seq Scan"
+	if filter, ok := m["Filter"]; ok {
+		node.Detail = ztype.ToString(filter)
+	}
+	if plans, ok := m["Plans"].([]interface{}); ok {
+		for i := range plans {
+			if child, ok := plans[i].(map[string]interface{}); ok {
+				node.Children = append(node.Children, postgresPlan(child))
+			}
+		}
+	}
+	return node
+}
+
+// explainSQLite builds the tree of the rows of EXPLAIN QUERY PLAN from their id and parent
+func explainSQLite(rows ztype.Maps) *Plan {
+	root := &Plan{Type: "QUERY PLAN"}
+	nodes := map[int]*Plan{0: root}
+	for _, row := range rows {
+		detail := row.Get("detail").String()
+		node := &Plan{Type: detail, Detail: detail}
+		if m := sqlitePlanDetail.FindStringSubmatch(detail); m != nil {
+			node.Type, node.Table, node.Index = m[1], m[2], m[3]
+			if strings.Contains(m[0], "PRIMARY KEY") {
+				node.Index = "PRIMARY KEY"
+			}
+			node.FullScan = m[1] == "SCAN" && node.Index == ""
+		}
+		nodes[row.Get("id").Int()] = node
+		parent, ok := nodes[row.Get("parent").Int()]
+		if !ok {
+			parent = root
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	if len(root.Children) == 1 {
+		return root.Children[0]
+	}
+	return root
+}
+
+// explainClickHouse builds the tree of the indented lines of EXPLAIN indexes = 1,
+// the index lines of a ReadFrom step are kept as its detail and a step without
+// an index condition is a full scan
+func explainClickHouse(lines []string) *Plan {
+	type level struct {
+		node   *Plan
+		indent int
+	}
+	root := &Plan{Type: "QUERY PLAN"}
+	stack := []level{{node: root, indent: -1}}
+	var (
+		read       *Plan
+		readIndent int
+		details    []string
+	)
+	finish := func() {
+		if read == nil {
+			return
+		}
+		read.Detail = strings.Join(details, "; ")
+		read.FullScan = true
+		section := ""
+		for _, d := range details {
+			switch {
+			case d == "PrimaryKey" || d == "MinMax" || d == "Partition" || d == "Skip":
+				section = d
+			case strings.HasPrefix(d, "Name: "):
+				section = strings.TrimPrefix(d, "Name: ")
+			case strings.HasPrefix(d, "Condition: ") && d != "Condition: true":
+				read.FullScan = false
+				if read.Index == "" {
+					read.Index = section
+				}
+			}
+		}
+		read, details = nil, nil
+	}
+
+	for _, line := range lines {
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		step := strings.Index(text, " (")
+		if step > 0 && strings.Contains(text[:step], ":") {
+			step = -1
+		}
+		if read != nil && indent >= readIndent && step < 0 {
+			if text != "Indexes:" && text != "Keys:" {
+				details = append(details, text)
+			}
+			continue
+		}
+		finish()
+
+		node := &Plan{Type: text}
+		if step > 0 {
+			node.Type = text[:step]
+			if strings.HasPrefix(node.Type, "ReadFrom") {
+				node.Table = strings.TrimSuffix(text[step+2:], ")")
+			}
+		}
+		for len(stack) > 1 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1].node
+		parent.Children = append(parent.Children, node)
+		stack = append(stack, level{node: node, indent: indent})
+		if strings.HasPrefix(node.Type, "ReadFrom") {
+			read, readIndent = node, indent
+		}
+	}
+	finish()
+
+	if len(root.Children) == 1 {
+		return root.Children[0]
+	}
+	return root
+}
@@ -0,0 +1,75 @@
+package zdb
+
+import (
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+)
+
+func TestExplainMySQL(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	plan, err := explainMySQL(`{"query_block": {"select_id": 1, "cost_info": {"query_cost": "3.75"},
+		"ordering_operation": {"using_filesort": true, "nested_loop": [
+			{"table": {"table_name": "u", "access_type": "ALL", "rows_examined_per_scan": 10, "cost_info": {"prefix_cost": "1.25"}}},
+			{"table": {"table_name": "o", "access_type": "ref", "key": "idx_user", "rows_examined_per_scan": 2, "cost_info": {"prefix_cost": "3.75"}}}
+		]}}}`)
+	tt.NoError(err)
+	tt.Equal(`QUERY BLOCK cost=3.75
+  ORDERING OPERATION
+    ALL on u rows=10 cost=1.25 [FULL SCAN]
+    ref on o using idx_user rows=2 cost=3.75`, plan.String())
+	tt.Equal(1, len(plan.FullScans()))
+	tt.Equal("u", plan.FullScans()[0].Table)
+
+	_, err = explainMySQL("not json")
+	tt.EqualTrue(err != nil)
+}
+
+func TestExplainPostgres(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	plan, err := explainPostgres(`[{"Plan": {"Node Type": "Hash Join", "Total Cost": 35.5, "Plan Rows": 12, "Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "orders", "Total Cost": 20, "Plan Rows": 100, "Actual Rows": 98, "Filter": "(amount > 10)"},
+		{"Node Type": "Hash", "Total Cost": 8, "Plan Rows": 4, "Plans": [
+			{"Node Type": "Index Scan", "Relation Name": "users", "Index Name": "users_pkey", "Total Cost": 8, "Plan Rows": 4}
+		]}
+	]}, "Planning Time": 0.1}]`)
+	tt.NoError(err)
+	tt.Equal(`Hash Join rows=12 cost=35.5
+  Seq Scan on orders rows=100 actual_rows=98 cost=20 [FULL SCAN]
+  Hash rows=4 cost=8
+    Index Scan on users using users_pkey rows=4 cost=8`, plan.String())
+	tt.Equal("(amount > 10)", plan.FullScans()[0].Detail)
+}
+
+func TestExplainClickHouse(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	plan := explainClickHouse([]string{
+		"Expression ((Projection + Before ORDER BY))",
+		"  Filter (WHERE)",
+		"    ReadFromMergeTree (default.events)",
+		"    Indexes:",
+		"      PrimaryKey",
+		"        Keys:",
+		"          id",
+		"        Condition: (id in [1, 1])",
+		"        Parts: 1/1",
+		"        Granules: 1/8",
+	})
+	tt.Equal(`Expression
+  Filter
+    ReadFromMergeTree on default.events using PrimaryKey`, plan.String())
+	tt.Equal(0, len(plan.FullScans()))
+
+	plan = explainClickHouse([]string{
+		"Expression ((Projection + Before ORDER BY))",
+		"  ReadFromMergeTree (default.events)",
+		"  Indexes:",
+		"    PrimaryKey",
+		"      Condition: true",
+		"      Parts: 1/1",
+	})
+	tt.Equal(1, len(plan.FullScans()))
+}
@@ -0,0 +1,185 @@
+package zdb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver"
+)
+
+// GuardOptions configures the write-safety guard of the DB, the zero value disables it
+type GuardOptions struct {
+	// Audit receives every statement run inside Unsafe,
+	// the statements are logged as warnings when it is nil
+	Audit func(reason, query string, args []interface{})
+	// LargeTables lists the tables that Find must read with Limit
+	LargeTables []string
+	// MaxRows caps the rows a query of the DB scans into maps, 0 is unlimited,
+	// the rows beyond it are dropped and ErrTooManyRows is returned
+	MaxRows int
+	// RequireWhere rejects UPDATE and DELETE statements without WHERE, including those of Exec
+	RequireWhere bool
+	// BlockDDL rejects CREATE, ALTER, DROP, TRUNCATE and RENAME outside Migration
+	BlockDDL bool
+}
+
+var ddlVerbs = map[string]bool{"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "RENAME": true}
+
+var statementVerbs = map[string]bool{
+	"SELECT": true, "INSERT": true, "UPDATE": true, "DELETE": true, "REPLACE": true, "MERGE": true,
+	"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "RENAME": true,
+}
+
+// Guard returns a DB with the write-safety guard changed by fn, the DB itself is unchanged
+func (e *DB) Guard(fn func(o *GuardOptions)) *DB {
+	nEngine := *e
+	fn(&nEngine.guard)
+	if len(nEngine.guard.LargeTables) > 0 {
+		nEngine.guard.LargeTables = append([]string(nil), nEngine.guard.LargeTables...)
+	}
+	return &nEngine
+}
+
+// Unsafe runs run with the guard turned off, reason is required and every
+// statement executed by run is passed to GuardOptions.Audit together with it
+func (e *DB) Unsafe(reason string, run DBCallback) error {
+	if strings.TrimSpace(reason) == "" {
+		return errors.New("guard error: unsafe requires a reason")
+	}
+	nEngine := *e
+	nEngine.unsafe = reason
+	return run(&nEngine)
+}
+
+// checkStatement audits the statement inside Unsafe, otherwise it is rejected when it breaks the guard
+func (e *DB) checkStatement(query string, args []interface{}) error {
+	if e.unsafe != "" {
+		if e.guard.Audit != nil {
+			e.guard.Audit(e.unsafe, query, args)
+		} else {
+			log.Warnf("unsafe [%s]: %s %v\n", e.unsafe, query, args)
+		}
+		return nil
+	}
+	if !e.guard.RequireWhere && !e.guard.BlockDDL {
+		return nil
+	}
+
+	d := driver.Typ(0)
+	if e.driver != nil {
+		d = e.driver.Value()
+	}
+	for _, stmt := range splitStatement(d, query) {
+		verb, where := statementVerb(stmt)
+		switch {
+		case e.guard.BlockDDL && ddlVerbs[verb] && !e.migrating:
+			return fmt.Errorf("guard error: %s is only allowed in Migration", verb)
+		case e.guard.RequireWhere && (verb == "UPDATE" || verb == "DELETE") && !where:
+			return fmt.Errorf("guard error: %s without WHERE", verb)
+		}
+	}
+	return nil
+}
+
+// checkFind rejects a SELECT without LIMIT on the large tables
+func (e *DB) checkFind(table string, b *builder.SelectBuilder) error {
+	if e.unsafe != "" || len(e.guard.LargeTables) == 0 || b.HasLimit() {
+		return nil
+	}
+	fields := strings.Fields(table)
+	if len(fields) == 0 {
+		return nil
+	}
+	name := strings.Trim(fields[0], "`\"[]")
+	for _, t := range e.guard.LargeTables {
+		if strings.EqualFold(t, name) {
+			return fmt.Errorf("guard error: find on large table %s requires Limit", name)
+		}
+	}
+	return nil
+}
+
+// maxRows returns the rows a query may scan, 0 is unlimited
+func (e *DB) maxRows() int {
+	if e.unsafe != "" {
+		return 0
+	}
+	return e.guard.MaxRows
+}
+
+// splitStatement returns the upper case words outside of parentheses, quotes
+// and comments of every statement of query, statements are split by ';'
+func splitStatement(d driver.Typ, query string) [][]string {
+	var (
+		stmts [][]string
+		words []string
+		depth int
+	)
+	backslash := d == driver.MySQL || d == driver.Doris || d == driver.ClickHouse
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			for i++; i < len(query) && query[i] != ch; i++ {
+				if backslash && query[i] == '\\' {
+					i++
+				}
+			}
+		case ch == '[' && d == driver.MsSQL:
+			for i++; i < len(query) && query[i] != ']'; i++ {
+			}
+		case ch == '-' && strings.HasPrefix(query[i:], "--"):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case ch == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 3
+			}
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case ch == ';' && depth <= 0:
+			stmts, words = append(stmts, words), nil
+		case isWordChar(ch):
+			start := i
+			for i+1 < len(query) && isWordChar(query[i+1]) {
+				i++
+			}
+			if depth <= 0 {
+				words = append(words, strings.ToUpper(query[start:i+1]))
+			}
+		}
+	}
+	return append(stmts, words)
+}
+
+// statementVerb returns the verb of the words of a statement, the CTEs of WITH are skipped,
+// and whether WHERE follows the verb
+func statementVerb(words []string) (string, bool) {
+	for i := range words {
+		if !statementVerbs[words[i]] {
+			if i == 0 && words[i] != "WITH" {
+				return words[i], false
+			}
+			continue
+		}
+		for _, w := range words[i+1:] {
+			if w == "WHERE" {
+				return words[i], true
+			}
+		}
+		return words[i], false
+	}
+	return "", false
+}
+
+func isWordChar(ch byte) bool {
+	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
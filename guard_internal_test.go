@@ -0,0 +1,36 @@
+package zdb
+
+import (
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zdb/driver"
+)
+
+func TestStatementVerb(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	for query, want := range map[string][]string{
+		`UPDATE t SET a = 1`: {"UPDATE", ""},
+		`update t set a = (select b from s where s.id = t.id)`:      {"UPDATE", ""},
+		`DELETE FROM t WHERE id = 1`:                                {"DELETE", "WHERE"},
+		`DELETE FROM t -- WHERE id = 1`:                             {"DELETE", ""},
+		`DELETE FROM t /* WHERE */ WHERE id = 1`:                    {"DELETE", "WHERE"},
+		`UPDATE t SET a = 'x WHERE y'`:                              {"UPDATE", ""},
+		`WITH x AS (SELECT id FROM s WHERE a = 1) DELETE FROM t`:    {"DELETE", ""},
+		"WITH RECURSIVE x AS (SELECT 1) UPDATE t SET a = 1 WHERE 1": {"UPDATE", "WHERE"},
+		`drop table t`:                  {"DROP", ""},
+		`SELECT * FROM t WHERE a = ';'`: {"SELECT", "WHERE"},
+	} {
+		stmts := splitStatement(driver.SQLite, query)
+		tt.Equal(1, len(stmts))
+		verb, where := statementVerb(stmts[0])
+		tt.Equal(want[0], verb)
+		tt.Equal(want[1] != "", where)
+	}
+
+	stmts := splitStatement(driver.MySQL, `SELECT 'a\'; DROP' FROM t; TRUNCATE t`)
+	tt.Equal(2, len(stmts))
+	verb, _ := statementVerb(stmts[1])
+	tt.Equal("TRUNCATE", verb)
+}
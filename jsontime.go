@@ -0,0 +1,163 @@
+package zdb
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/sohaha/zlsgo/zstring"
+)
+
+// JsonTimeOptions controls how JsonTime is rendered, parsed and written
+type JsonTimeOptions struct {
+	// Location converts the time before rendering or writing and parses times without offset,
+	// nil keeps the location of the time and parses in time.Local
+	Location *time.Location
+	// Layout is used by String, MarshalJSON and the parsing of text values
+	Layout string
+	// Zero is the text rendered for the zero time
+	Zero string
+	// ZeroAsNull renders the zero time as JSON null and writes it as NULL
+	ZeroAsNull bool
+}
+
+// DefaultJsonTimeOptions is used by JsonTime and by databases without their own options
+var DefaultJsonTimeOptions = JsonTimeOptions{
+	Layout: "2006-01-02 15:04:05",
+	Zero:   "0000-00-00 00:00:00",
+}
+
+var errJsonTimeInvalid = errors.New("invalid time value")
+
+// JsonTimeOptions sets how JsonTime values are written and scanned by the DB,
+// the JSON rendering always follows DefaultJsonTimeOptions
+func (e *DB) JsonTimeOptions(fn func(o *JsonTimeOptions)) {
+	options := DefaultJsonTimeOptions
+	fn(&options)
+	e.RegisterCodec(JsonTime{}, Codec{
+		Encode: func(v interface{}) (interface{}, error) {
+			return options.value(v.(JsonTime)), nil
+		},
+		Decode: func(src interface{}) (interface{}, error) {
+			t, err := options.parse(src)
+			return JsonTime(t), err
+		},
+	})
+}
+
+func (o JsonTimeOptions) format(t time.Time) string {
+	if t.IsZero() {
+		return o.Zero
+	}
+	if o.Location != nil {
+		t = t.In(o.Location)
+	}
+	return t.Format(o.Layout)
+}
+
+func (o JsonTimeOptions) value(j JsonTime) driver.Value {
+	t := time.Time(j)
+	if t.IsZero() {
+		if o.ZeroAsNull {
+			return nil
+		}
+		return t
+	}
+	if o.Location != nil {
+		t = t.In(o.Location)
+	}
+	return t
+}
+
+// parse converts the scanned or decoded value into a time
+func (o JsonTimeOptions) parse(src interface{}) (time.Time, error) {
+	switch v := src.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		if o.Location != nil && !v.IsZero() {
+			v = v.In(o.Location)
+		}
+		return v, nil
+	case JsonTime:
+		return o.parse(time.Time(v))
+	case int64:
+		return o.parse(time.Unix(v, 0))
+	case []byte:
+		return o.parseString(zstring.Bytes2String(v))
+	case string:
+		return o.parseString(v)
+	}
+	return time.Time{}, errJsonTimeInvalid
+}
+
+func (o JsonTimeOptions) parseString(s string) (time.Time, error) {
+	if s == "" || s == o.Zero || s == DefaultJsonTimeOptions.Zero {
+		return time.Time{}, nil
+	}
+	loc := o.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	layouts := append([]string{o.Layout, time.RFC3339Nano}, timeLayouts...)
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return o.parse(t)
+		}
+	}
+	return time.Time{}, errJsonTimeInvalid
+}
+
+func (j JsonTime) String() string {
+	return DefaultJsonTimeOptions.format(time.Time(j))
+}
+
+func (j JsonTime) Time() time.Time {
+	return time.Time(j)
+}
+
+func (j JsonTime) MarshalJSON() ([]byte, error) {
+	if time.Time(j).IsZero() && DefaultJsonTimeOptions.ZeroAsNull {
+		return []byte("null"), nil
+	}
+	res := bytes.NewBufferString("\"")
+	res.WriteString(j.String())
+	res.WriteString("\"")
+	return res.Bytes(), nil
+}
+
+// UnmarshalJSON parses a quoted time in the configured layout, null and the zero text become the zero time
+func (j *JsonTime) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		*j = JsonTime{}
+		return nil
+	}
+	s, err := strconv.Unquote(s)
+	if err != nil {
+		return errJsonTimeInvalid
+	}
+	t, err := DefaultJsonTimeOptions.parseString(s)
+	if err != nil {
+		return err
+	}
+	*j = JsonTime(t)
+	return nil
+}
+
+// Scan implements sql.Scanner, time values and text timestamps are accepted
+func (j *JsonTime) Scan(src interface{}) error {
+	t, err := DefaultJsonTimeOptions.parse(src)
+	if err != nil {
+		return err
+	}
+	*j = JsonTime(t)
+	return nil
+}
+
+// Value implements driver.Valuer
+func (j JsonTime) Value() (driver.Value, error) {
+	return DefaultJsonTimeOptions.value(j), nil
+}
@@ -12,5 +12,6 @@ func (e *DB) Migration(fn func(db *DB, d driver.Dialect) error) error {
 	defer e.putSessionPool(s, false)
 
 	nEngine := e.withSession(s)
+	nEngine.migrating = true
 	return fn(nEngine, s.config.driver)
 }
@@ -0,0 +1,521 @@
+package zdb
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/zreflect"
+	"github.com/sohaha/zlsgo/zstring"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zdb/schema"
+)
+
+type (
+	// TableNamer is implemented by models that declare their table name
+	TableNamer interface {
+		TableName() string
+	}
+	modelField struct {
+		name       string
+		column     string
+		keys       []string
+		index      []int
+		primaryKey bool
+		omitEmpty  bool
+		readonly   bool
+		insertOnly bool
+		json       bool
+	}
+	modelRelation struct {
+		name    string
+		kind    string
+		index   []int
+		elem    reflect.Type
+		options map[string]string
+		slice   bool
+		ptr     bool
+	}
+	modelInfo struct {
+		typ        reflect.Type
+		table      string
+		fields     []*modelField
+		relations  []*modelRelation
+		primaryKey *modelField
+	}
+	writeMode uint8
+	modelKey  struct {
+		typ   reflect.Type
+		idKey string
+	}
+)
+
+const (
+	writeInsert writeMode = iota
+	writeUpdate
+)
+
+const tagName = "zdb"
+
+var (
+	modelCache = sync.Map{}
+	modelTags  = []string{tagName, zreflect.Tag, "json"}
+
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+	errModelInvalid = errors.New("model must be a struct")
+	errModelNoPK    = errors.New("model primary key not found")
+)
+
+func parseModel(typ reflect.Type, idKey string) (*modelInfo, error) {
+	if typ == nil {
+		return nil, errModelInvalid
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, errModelInvalid
+	}
+
+	key := modelKey{typ: typ, idKey: idKey}
+	if m, ok := modelCache.Load(key); ok {
+		return m.(*modelInfo), nil
+	}
+
+	m := &modelInfo{
+		typ:   typ,
+		table: modelTableName(typ),
+	}
+	m.parseFields(typ, nil, nil, false, map[reflect.Type]bool{typ: true})
+
+	for _, f := range m.fields {
+		if f.primaryKey {
+			m.primaryKey = f
+			break
+		}
+	}
+	if m.primaryKey == nil {
+		for _, f := range m.fields {
+			if f.column == idKey && len(f.keys) == 1 {
+				f.primaryKey = true
+				m.primaryKey = f
+				break
+			}
+		}
+	}
+
+	modelCache.Store(key, m)
+	return m, nil
+}
+
+// parseFields flattens embedded structs into the model, named struct fields and
+// embedded structs tagged with prefix are mapped from "prefix.col" or "prefix__col"
+func (m *modelInfo) parseFields(typ reflect.Type, index []int, prefix []string, readonly bool, visited map[reflect.Type]bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		structField := typ.Field(i)
+		ft := structField.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		embedded := structField.Anonymous && isNestedStruct(ft)
+		if zstring.IsLcfirst(structField.Name) && !(embedded && structField.Type.Kind() == reflect.Struct) {
+			continue
+		}
+
+		column, opts, tagged := modelTag(structField)
+		if column == "" {
+			continue
+		}
+
+		fieldIndex := make([]int, len(index)+1)
+		copy(fieldIndex, index)
+		fieldIndex[len(index)] = i
+
+		if rel := parseRelation(structField, opts, fieldIndex); rel != nil {
+			if len(prefix) == 0 {
+				m.relations = append(m.relations, rel)
+			}
+			continue
+		}
+
+		if embedded || (isNestedStruct(ft) && !hasTagOption(opts, "json")) {
+			if visited[ft] {
+				continue
+			}
+			visited[ft] = true
+			if embedded && !(tagged && hasTagOption(opts, "prefix")) {
+				m.parseFields(ft, fieldIndex, prefix, readonly, visited)
+			} else {
+				m.parseFields(ft, fieldIndex, append(prefix[:len(prefix):len(prefix)], column), true, visited)
+			}
+			delete(visited, ft)
+			continue
+		}
+
+		f := &modelField{
+			name:     structField.Name,
+			column:   column,
+			keys:     modelKeys(prefix, column),
+			index:    fieldIndex,
+			readonly: readonly,
+		}
+		for _, opt := range strings.Split(opts, ",") {
+			switch strings.TrimSpace(opt) {
+			case "pk", "primaryKey":
+				f.primaryKey = len(prefix) == 0
+			case "omitempty":
+				f.omitEmpty = true
+			case "readonly":
+				f.readonly = true
+			case "insertonly":
+				f.insertOnly = true
+			case "json":
+				f.json = true
+			}
+		}
+		if isJSONType(ft) {
+			f.json = true
+		}
+		m.fields = append(m.fields, f)
+	}
+}
+
+// isJSONType reports whether the Go type maps to schema.JSON, such fields are
+// written and scanned as JSON without the json tag option
+func isJSONType(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return schema.NewFieldForValue("", reflect.Zero(typ).Interface()).DataType == schema.JSON
+}
+
+func modelTag(field reflect.StructField) (name, opts string, tagged bool) {
+	for _, t := range modelTags {
+		v := field.Tag.Get(t)
+		if v == "" {
+			continue
+		}
+		if v == "-" {
+			return "", "", true
+		}
+		name, opts = v, ""
+		if i := strings.IndexByte(v, ','); i >= 0 {
+			name, opts = v[:i], v[i+1:]
+		}
+		if name == "" {
+			name = field.Name
+		}
+		return name, opts, true
+	}
+	return field.Name, "", false
+}
+
+func hasTagOption(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if strings.TrimSpace(o) == opt {
+			return true
+		}
+	}
+	return false
+}
+
+func modelKeys(prefix []string, column string) []string {
+	if len(prefix) == 0 {
+		return []string{column}
+	}
+	return []string{
+		strings.Join(prefix, ".") + "." + column,
+		strings.Join(prefix, "__") + "__" + column,
+	}
+}
+
+// isNestedStruct reports whether typ is mapped field by field instead of as a single value
+func isNestedStruct(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct || typ.ConvertibleTo(timeType) {
+		return false
+	}
+	if typ.Implements(valuerType) || reflect.PtrTo(typ).Implements(scannerType) || globalCodecs.has(typ) {
+		return false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if !zstring.IsLcfirst(typ.Field(i).Name) || typ.Field(i).Anonymous {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldByIndex walks the index like reflect.Value.FieldByIndex,
+// nil embedded pointers are allocated when alloc is set or reported as missing
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func modelTableName(typ reflect.Type) string {
+	if n, ok := reflect.New(typ).Interface().(TableNamer); ok {
+		return n.TableName()
+	}
+	if n, ok := reflect.Zero(typ).Interface().(TableNamer); ok {
+		return n.TableName()
+	}
+	return zstring.CamelCaseToSnakeCase(typ.Name())
+}
+
+// writable reports whether the field is written in the given mode,
+// selected columns bypass omitempty and the empty primary key check
+func (m *modelInfo) writable(f *modelField, field reflect.Value, mode writeMode, only, omit []string) bool {
+	if f.readonly || (mode == writeUpdate && (f.insertOnly || f.primaryKey)) {
+		return false
+	}
+	if inColumns(omit, f.column) {
+		return false
+	}
+	if len(only) > 0 {
+		return inColumns(only, f.column)
+	}
+	if f.omitEmpty || f.primaryKey {
+		return !field.IsZero()
+	}
+	return true
+}
+
+// columns returns the columns and values of the struct to be written
+func (m *modelInfo) columns(v reflect.Value, mode writeMode, only, omit []string) ([]string, []interface{}, error) {
+	v = reflect.Indirect(v)
+	cols := make([]string, 0, len(m.fields))
+	args := make([]interface{}, 0, len(m.fields))
+	for _, f := range m.fields {
+		field, ok := fieldByIndex(v, f.index, false)
+		if !ok || !m.writable(f, field, mode, only, omit) {
+			continue
+		}
+		cols = append(cols, f.column)
+		val, err := f.value(field)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, val)
+	}
+	return cols, args, nil
+}
+
+// batchColumns returns the columns shared by all rows, an omitempty column
+// must be set in every row or in none, otherwise the empty rows would lose the default of the column
+func (m *modelInfo) batchColumns(rows reflect.Value, mode writeMode, only, omit []string) ([]string, [][]interface{}, error) {
+	fields := make([]*modelField, 0, len(m.fields))
+	for _, f := range m.fields {
+		set := 0
+		for i := 0; i < rows.Len(); i++ {
+			field, ok := fieldByIndex(reflect.Indirect(rows.Index(i)), f.index, false)
+			if ok && m.writable(f, field, mode, only, omit) {
+				set++
+			}
+		}
+		if set == 0 {
+			continue
+		}
+		if set < rows.Len() {
+			return nil, nil, fmt.Errorf("batch error: column %s is empty in %d of %d rows, write them in separate batches", f.column, rows.Len()-set, rows.Len())
+		}
+		fields = append(fields, f)
+	}
+
+	cols := make([]string, 0, len(fields))
+	for _, f := range fields {
+		cols = append(cols, f.column)
+	}
+	args := make([][]interface{}, 0, rows.Len())
+	for i := 0; i < rows.Len(); i++ {
+		row := reflect.Indirect(rows.Index(i))
+		rowArgs := make([]interface{}, 0, len(fields))
+		for _, f := range fields {
+			if field, ok := fieldByIndex(row, f.index, false); ok {
+				val, err := f.value(field)
+				if err != nil {
+					return nil, nil, err
+				}
+				rowArgs = append(rowArgs, val)
+			} else {
+				rowArgs = append(rowArgs, nil)
+			}
+		}
+		args = append(args, rowArgs)
+	}
+	return cols, args, nil
+}
+
+// value returns the value written to the column, json fields are marshalled to a string
+func (f *modelField) value(field reflect.Value) (interface{}, error) {
+	if f.json {
+		return encodeJSON(field)
+	}
+	return field.Interface(), nil
+}
+
+func inColumns(cols []string, col string) bool {
+	for i := range cols {
+		if cols[i] == col {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *modelInfo) primaryValue(v reflect.Value) (interface{}, bool) {
+	if m.primaryKey == nil {
+		return nil, false
+	}
+	field, ok := fieldByIndex(reflect.Indirect(v), m.primaryKey.index, false)
+	if !ok {
+		return nil, false
+	}
+	return field.Interface(), !field.IsZero()
+}
+
+func (m *modelInfo) setPrimaryValue(v reflect.Value, id interface{}) error {
+	if m.primaryKey == nil {
+		return errModelNoPK
+	}
+	field, ok := fieldByIndex(reflect.Indirect(v), m.primaryKey.index, true)
+	if !ok || !field.CanSet() {
+		return errModelNoPK
+	}
+	return ztype.ValueConv(id, field.Addr())
+}
+
+// decode sets the struct fields from the row, columns are matched case-insensitively
+func (m *modelInfo) decode(row ztype.Map, v reflect.Value, codecs *codecRegistry) error {
+	v = reflect.Indirect(v)
+	for _, f := range m.fields {
+		val, ok := lookupColumn(row, f.keys)
+		if !ok || val == nil {
+			continue
+		}
+		field, ok := fieldByIndex(v, f.index, true)
+		if !ok {
+			continue
+		}
+		if ok, err := codecs.decode(val, field); ok {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if field.Type() == jsontimeType || field.Type() == jsontimePtrType {
+			if err := decodeJsonTime(val, field); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.json {
+			if err := decodeJSON(val, field); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := ztype.ValueConv(val, field.Addr(), codecs.convOption); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lookupColumn(row ztype.Map, keys []string) (interface{}, bool) {
+	for _, k := range keys {
+		if val, ok := row[k]; ok {
+			return val, true
+		}
+	}
+	for col, val := range row {
+		for _, k := range keys {
+			if strings.EqualFold(col, k) {
+				return val, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func encodeJSON(field reflect.Value) (interface{}, error) {
+	switch field.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		if field.IsNil() {
+			return nil, nil
+		}
+	}
+	switch v := field.Interface().(type) {
+	case zjson.Res:
+		return v.Raw(), nil
+	case *zjson.Res:
+		return v.Raw(), nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+	b, err := json.Marshal(field.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// decodeJSON unmarshals the column into the field, the value may be raw text
+// or already parsed by the column type decoding
+func decodeJSON(val interface{}, field reflect.Value) error {
+	var b []byte
+	switch v := val.(type) {
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+
+	switch field.Interface().(type) {
+	case zjson.Res:
+		field.Set(reflect.ValueOf(*zjson.ParseBytes(b)))
+		return nil
+	case *zjson.Res:
+		field.Set(reflect.ValueOf(zjson.ParseBytes(b)))
+		return nil
+	case string:
+		field.SetString(string(b))
+		return nil
+	}
+	return json.Unmarshal(b, field.Addr().Interface())
+}
+
+func decodeJsonTime(val interface{}, field reflect.Value) error {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(jsontimeType))
+		}
+		field = field.Elem()
+	}
+	return field.Addr().Interface().(*JsonTime).Scan(val)
+}
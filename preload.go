@@ -0,0 +1,409 @@
+package zdb
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/sohaha/zlsgo/zstring"
+	"github.com/sohaha/zlsgo/ztype"
+	"github.com/zlsgo/zdb/builder"
+)
+
+type (
+	// FindOption configures the typed queries
+	FindOption func(o *findOption)
+	findOption struct {
+		preloads []preloadOption
+	}
+	preloadOption struct {
+		name string
+		fn   func(b *builder.SelectBuilder) error
+	}
+)
+
+const (
+	relationHasOne    = "hasOne"
+	relationHasMany   = "hasMany"
+	relationBelongsTo = "belongsTo"
+	relationMany2Many = "many2many"
+)
+
+var errRelationNotFound = errors.New("relation not found")
+
+// Preload loads the relation declared on the struct field with one batched IN query,
+// fn can be used to filter or order the related rows
+func Preload(name string, fn ...func(b *builder.SelectBuilder) error) FindOption {
+	p := preloadOption{name: name}
+	if len(fn) > 0 {
+		p.fn = fn[0]
+	}
+	return func(o *findOption) {
+		o.preloads = append(o.preloads, p)
+	}
+}
+
+func newFindOption(opts []FindOption) *findOption {
+	o := &findOption{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// parseRelation parses relation declarations such as
+// `zdb:"orders,hasMany,foreignKey:user_id"` or `zdb:"roles,many2many:user_roles"`
+func parseRelation(field reflect.StructField, opts string, index []int) *modelRelation {
+	if opts == "" {
+		return nil
+	}
+	rel := &modelRelation{
+		name:    field.Name,
+		index:   index,
+		options: map[string]string{},
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		opt = strings.TrimSpace(opt)
+		key, val := opt, ""
+		if i := strings.IndexByte(opt, ':'); i > 0 {
+			key, val = opt[:i], opt[i+1:]
+		}
+		switch key {
+		case relationHasOne, relationHasMany, relationBelongsTo:
+			rel.kind = key
+		case relationMany2Many:
+			rel.kind = key
+			rel.options["joinTable"] = val
+		default:
+			if val != "" {
+				rel.options[key] = val
+			}
+		}
+	}
+	if rel.kind == "" {
+		return nil
+	}
+
+	typ := field.Type
+	if typ.Kind() == reflect.Slice {
+		rel.slice = true
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Ptr {
+		rel.ptr = true
+		typ = typ.Elem()
+	}
+	rel.elem = typ
+	return rel
+}
+
+func (m *modelInfo) relation(name string) *modelRelation {
+	for _, rel := range m.relations {
+		if rel.name == name {
+			return rel
+		}
+	}
+	return nil
+}
+
+func (m *modelInfo) field(column string) *modelField {
+	for _, f := range m.fields {
+		if f.column == column && len(f.keys) == 1 {
+			return f
+		}
+	}
+	return nil
+}
+
+func (rel *modelRelation) option(key, def string) string {
+	if v := rel.options[key]; v != "" {
+		return v
+	}
+	return def
+}
+
+func relationKey(v interface{}) string {
+	return ztype.ToString(v)
+}
+
+func itemKey(item reflect.Value, f *modelField) string {
+	v, ok := fieldByIndex(item, f.index, false)
+	if !ok || v.IsZero() {
+		return ""
+	}
+	return relationKey(v.Interface())
+}
+
+// preload loads the relations into items, which are addressable structs of the model
+func (e *DB) preload(m *modelInfo, items []reflect.Value, opt *findOption) error {
+	if len(items) == 0 {
+		return nil
+	}
+	for _, p := range opt.preloads {
+		rel := m.relation(p.name)
+		if rel == nil {
+			return errors.New(errRelationNotFound.Error() + ": " + p.name)
+		}
+		child, err := parseModel(rel.elem, e.idKey)
+		if err != nil {
+			return err
+		}
+
+		switch rel.kind {
+		case relationHasOne, relationHasMany:
+			err = e.preloadHas(m, child, rel, items, p.fn)
+		case relationBelongsTo:
+			err = e.preloadBelongsTo(m, child, rel, items, p.fn)
+		case relationMany2Many:
+			err = e.preloadMany2Many(m, child, rel, items, p.fn)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *DB) preloadHas(m, child *modelInfo, rel *modelRelation, items []reflect.Value, fn func(b *builder.SelectBuilder) error) error {
+	references := rel.option("references", m.primaryColumn(e.idKey))
+	foreignKey := rel.option("foreignKey", zstring.CamelCaseToSnakeCase(m.typ.Name())+"_id")
+
+	keys, err := m.columnValues(items, references)
+	if err != nil {
+		return err
+	}
+	rows, err := e.findRelated(child.table, foreignKey, keys, fn)
+	if err != nil {
+		return err
+	}
+
+	grouped := make(map[string][]ztype.Map, len(keys))
+	for i := range rows {
+		k := relationKey(rows[i][foreignKey])
+		grouped[k] = append(grouped[k], rows[i])
+	}
+
+	ref := m.field(references)
+	for _, item := range items {
+		if err = rel.assign(child, item, grouped[itemKey(item, ref)], e.codecs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *DB) preloadBelongsTo(m, child *modelInfo, rel *modelRelation, items []reflect.Value, fn func(b *builder.SelectBuilder) error) error {
+	foreignKey := rel.option("foreignKey", zstring.CamelCaseToSnakeCase(rel.name)+"_id")
+	references := rel.option("references", child.primaryColumn(e.idKey))
+
+	keys, err := m.columnValues(items, foreignKey)
+	if err != nil {
+		return err
+	}
+	rows, err := e.findRelated(child.table, references, keys, fn)
+	if err != nil {
+		return err
+	}
+
+	indexed := make(map[string][]ztype.Map, len(rows))
+	for i := range rows {
+		k := relationKey(rows[i][references])
+		indexed[k] = append(indexed[k], rows[i])
+	}
+
+	fk := m.field(foreignKey)
+	for _, item := range items {
+		if err = rel.assign(child, item, indexed[itemKey(item, fk)], e.codecs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *DB) preloadMany2Many(m, child *modelInfo, rel *modelRelation, items []reflect.Value, fn func(b *builder.SelectBuilder) error) error {
+	joinTable := rel.option("joinTable", "")
+	if joinTable == "" {
+		return errors.New("many2many relation requires a join table: " + rel.name)
+	}
+	references := rel.option("references", m.primaryColumn(e.idKey))
+	childReferences := rel.option("childReferences", child.primaryColumn(e.idKey))
+	joinForeignKey := rel.option("joinForeignKey", zstring.CamelCaseToSnakeCase(m.typ.Name())+"_id")
+	joinReferences := rel.option("joinReferences", zstring.CamelCaseToSnakeCase(child.typ.Name())+"_id")
+
+	keys, err := m.columnValues(items, references)
+	if err != nil {
+		return err
+	}
+	pairs, err := e.findRelated(joinTable, joinForeignKey, keys, func(b *builder.SelectBuilder) error {
+		b.Select(joinForeignKey, joinReferences)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	childKeys := make([]interface{}, 0, len(pairs))
+	seen := make(map[string]struct{}, len(pairs))
+	for i := range pairs {
+		k := relationKey(pairs[i][joinReferences])
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		childKeys = append(childKeys, pairs[i][joinReferences])
+	}
+	rows, err := e.findRelated(child.table, childReferences, childKeys, fn)
+	if err != nil {
+		return err
+	}
+
+	indexed := make(map[string]ztype.Map, len(rows))
+	for i := range rows {
+		indexed[relationKey(rows[i][childReferences])] = rows[i]
+	}
+	grouped := make(map[string][]ztype.Map, len(keys))
+	for i := range pairs {
+		row, ok := indexed[relationKey(pairs[i][joinReferences])]
+		if !ok {
+			continue
+		}
+		k := relationKey(pairs[i][joinForeignKey])
+		grouped[k] = append(grouped[k], row)
+	}
+
+	ref := m.field(references)
+	for _, item := range items {
+		if err = rel.assign(child, item, grouped[itemKey(item, ref)], e.codecs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findRelated runs the batched IN query of a relation, no rows is not an error
+func (e *DB) findRelated(table, column string, keys []interface{}, fn func(b *builder.SelectBuilder) error) (ztype.Maps, error) {
+	if len(keys) == 0 {
+		return ztype.Maps{}, nil
+	}
+	rows, err := e.Find(table, func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.In(column, keys...))
+		if fn != nil {
+			return fn(b)
+		}
+		return nil
+	})
+	if err == ErrNotFound {
+		return ztype.Maps{}, nil
+	}
+	return rows, err
+}
+
+// columnValues returns the distinct values of the column in items
+func (m *modelInfo) columnValues(items []reflect.Value, column string) ([]interface{}, error) {
+	f := m.field(column)
+	if f == nil {
+		return nil, errors.New("relation column not found: " + column)
+	}
+	keys := make([]interface{}, 0, len(items))
+	seen := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		v, ok := fieldByIndex(item, f.index, false)
+		if !ok || v.IsZero() {
+			continue
+		}
+		k := relationKey(v.Interface())
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		keys = append(keys, v.Interface())
+	}
+	return keys, nil
+}
+
+func (m *modelInfo) primaryColumn(idKey string) string {
+	if m.primaryKey != nil {
+		return m.primaryKey.column
+	}
+	return idKey
+}
+
+// assign decodes the related rows into the relation field of item
+func (rel *modelRelation) assign(child *modelInfo, item reflect.Value, rows []ztype.Map, codecs *codecRegistry) error {
+	field, ok := fieldByIndex(item, rel.index, true)
+	if !ok {
+		return nil
+	}
+
+	newElem := func(row ztype.Map) (reflect.Value, error) {
+		v := reflect.New(rel.elem)
+		if err := child.decode(row, v, codecs); err != nil {
+			return v, err
+		}
+		if rel.ptr {
+			return v, nil
+		}
+		return v.Elem(), nil
+	}
+
+	if !rel.slice {
+		if len(rows) == 0 {
+			return nil
+		}
+		v, err := newElem(rows[0])
+		if err != nil {
+			return err
+		}
+		field.Set(v)
+		return nil
+	}
+
+	list := reflect.MakeSlice(field.Type(), 0, len(rows))
+	for i := range rows {
+		v, err := newElem(rows[i])
+		if err != nil {
+			return err
+		}
+		list = reflect.Append(list, v)
+	}
+	field.Set(list)
+	return nil
+}
+
+// preloadValues runs the preloads on out, a pointer to a struct or a slice of structs
+func (e *DB) preloadValues(out reflect.Value, opt *findOption) error {
+	if len(opt.preloads) == 0 {
+		return nil
+	}
+	v := reflect.Indirect(out)
+	items := make([]reflect.Value, 0, 1)
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i)
+			if item.Kind() == reflect.Ptr {
+				if item.IsNil() {
+					continue
+				}
+				item = item.Elem()
+			}
+			items = append(items, item)
+		}
+	} else {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+		items = append(items, v)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	m, err := parseModel(items[0].Type(), e.idKey)
+	if err != nil {
+		return err
+	}
+	return e.preload(m, items, opt)
+}
@@ -0,0 +1,109 @@
+//go:build go1.18
+// +build go1.18
+
+package zdb_test
+
+import (
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zdb"
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver/sqlite3"
+	"github.com/zlsgo/zdb/testdata"
+)
+
+type preloadOrder struct {
+	ID     int    `zdb:"id"`
+	UserID int    `zdb:"user_id"`
+	Title  string `zdb:"title"`
+}
+
+func (preloadOrder) TableName() string { return "preload_order" }
+
+type preloadRole struct {
+	ID   int    `zdb:"id"`
+	Name string `zdb:"name"`
+}
+
+func (preloadRole) TableName() string { return "preload_role" }
+
+type preloadUser struct {
+	ID      int            `zdb:"id"`
+	Name    string         `zdb:"name"`
+	Orders  []preloadOrder `zdb:"orders,hasMany,foreignKey:user_id"`
+	Last    *preloadOrder  `zdb:"last,hasOne,foreignKey:user_id"`
+	Roles   []*preloadRole `zdb:"roles,many2many:preload_user_role,joinForeignKey:user_id,joinReferences:role_id"`
+	Missing []preloadOrder `zdb:"missing,hasMany,foreignKey:user_id"`
+}
+
+func (preloadUser) TableName() string { return "preload_user" }
+
+type preloadOrderOwner struct {
+	ID     int          `zdb:"id"`
+	UserID int          `zdb:"user_id"`
+	User   *preloadUser `zdb:"user,belongsTo"`
+}
+
+func (preloadOrderOwner) TableName() string { return "preload_order" }
+
+func TestPreload(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("preload")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	for _, sql := range []string{
+		`DROP TABLE IF EXISTS preload_user`,
+		`DROP TABLE IF EXISTS preload_order`,
+		`DROP TABLE IF EXISTS preload_role`,
+		`DROP TABLE IF EXISTS preload_user_role`,
+		`CREATE TABLE preload_user (id INTEGER PRIMARY KEY, name TEXT)`,
+		`CREATE TABLE preload_order (id INTEGER PRIMARY KEY, user_id INTEGER, title TEXT)`,
+		`CREATE TABLE preload_role (id INTEGER PRIMARY KEY, name TEXT)`,
+		`CREATE TABLE preload_user_role (user_id INTEGER, role_id INTEGER)`,
+		`INSERT INTO preload_user (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c')`,
+		`INSERT INTO preload_order (id, user_id, title) VALUES (1, 1, 'o1'), (2, 1, 'o2'), (3, 2, 'o3')`,
+		`INSERT INTO preload_role (id, name) VALUES (1, 'admin'), (2, 'editor')`,
+		`INSERT INTO preload_user_role (user_id, role_id) VALUES (1, 1), (1, 2), (2, 2)`,
+	} {
+		_, err = db.Exec(sql)
+		tt.NoError(err)
+	}
+
+	users, err := zdb.Find[preloadUser](db, "preload_user", func(b *builder.SelectBuilder) error {
+		b.OrderBy("id").Asc()
+		return nil
+	}, zdb.Preload("Orders", func(b *builder.SelectBuilder) error {
+		b.OrderBy("id").Desc()
+		return nil
+	}), zdb.Preload("Last"), zdb.Preload("Roles"))
+	tt.NoError(err)
+	tt.Equal(3, len(users))
+	tt.Equal(2, len(users[0].Orders))
+	tt.Equal("o2", users[0].Orders[0].Title)
+	tt.Equal(1, len(users[1].Orders))
+	tt.Equal(0, len(users[2].Orders))
+	tt.Equal(2, len(users[0].Roles))
+	tt.Equal("editor", users[1].Roles[0].Name)
+	tt.EqualTrue(users[0].Last != nil)
+	tt.EqualTrue(users[2].Last == nil)
+	tt.Equal(0, len(users[0].Missing))
+
+	repo, err := zdb.NewRepository[preloadOrderOwner](db)
+	tt.NoError(err)
+	order, err := repo.Preload("User").Get(3)
+	tt.NoError(err)
+	tt.Equal("b", order.User.Name)
+
+	_, err = zdb.FindOne[preloadUser](db, "preload_user", nil, zdb.Preload("Unknown"))
+	tt.EqualTrue(err != nil)
+}
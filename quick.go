@@ -8,13 +8,22 @@ import (
 )
 
 func (e *DB) QueryToMaps(query string, args ...interface{}) (ztype.Maps, error) {
-	rows, err := e.Query(query, args...)
+	args, err := e.codecs.encodeArgs(args)
+	if err != nil {
+		return ztype.Maps{}, err
+	}
+	return e.queryToMaps(query, args)
+}
+
+// queryToMaps is QueryToMaps with args that are already encoded
+func (e *DB) queryToMaps(query string, args []interface{}) (ztype.Maps, error) {
+	rows, err := e.query(query, args)
 	if err != nil {
 		return ztype.Maps{}, err
 	}
 	defer rows.Close()
 
-	result, _, err := ScanToMap(rows)
+	result, _, err := e.scanToMap(rows)
 	return result, err
 }
 
@@ -25,7 +34,7 @@ func (e *DB) QueryTo(out interface{}, query string, args ...interface{}) error {
 	}
 	defer rows.Close()
 
-	result, _, err := ScanToMap(rows)
+	result, _, err := e.scanToMap(rows)
 	if err != nil {
 		return err
 	}
@@ -34,8 +43,8 @@ func (e *DB) QueryTo(out interface{}, query string, args ...interface{}) error {
 		if len(result) == 0 {
 			return ErrNotFound
 		}
-		return ztype.ValueConv(result[0], v, convOption)
+		return convMap(result[0], v, e.codecs)
 	}
 
-	return ztype.ValueConv(result, v, convOption)
+	return convMaps(result, v, e.codecs)
 }
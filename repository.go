@@ -0,0 +1,138 @@
+//go:build go1.18
+// +build go1.18
+
+package zdb
+
+import (
+	"reflect"
+
+	"github.com/sohaha/zlsgo/zreflect"
+	"github.com/zlsgo/zdb/builder"
+)
+
+// Repository is a typed CRUD helper built on struct metadata
+type Repository[T any] struct {
+	db    *DB
+	model *modelInfo
+	opts  []FindOption
+}
+
+// NewRepository returns a repository for T, the table is resolved from TableName()
+func NewRepository[T any](e *DB) (*Repository[T], error) {
+	var t T
+	m, err := parseModel(reflect.TypeOf(t), e.idKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository[T]{db: e, model: m}, nil
+}
+
+// Table returns the table name of the repository
+func (r *Repository[T]) Table() string {
+	return r.model.table
+}
+
+// Preload returns a repository that preloads the relation on every read
+func (r *Repository[T]) Preload(name string, fn ...func(b *builder.SelectBuilder) error) *Repository[T] {
+	n := *r
+	n.opts = append(append([]FindOption{}, r.opts...), Preload(name, fn...))
+	return &n
+}
+
+// Get returns the row with the given primary key
+func (r *Repository[T]) Get(id interface{}) (T, error) {
+	var m T
+	if r.model.primaryKey == nil {
+		return m, errModelNoPK
+	}
+	data, err := r.db.FindOne(r.model.table, func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.EQ(r.model.primaryKey.column, id))
+		return nil
+	})
+	if err != nil {
+		return m, err
+	}
+
+	v := zreflect.ValueOf(&m)
+	if err = convMap(data, v, r.db.codecs); err != nil {
+		return m, err
+	}
+	return m, r.db.preloadValues(v, newFindOption(r.opts))
+}
+
+// Create inserts the struct and writes the generated ID back into it,
+// the ID is read from the primary key of the model
+func (r *Repository[T]) Create(v *T) error {
+	rv := reflect.ValueOf(v)
+	db := r.db
+	if r.model.primaryKey != nil && r.model.primaryKey.column != db.idKey {
+		nEngine := *db
+		nEngine.idKey = r.model.primaryKey.column
+		db = &nEngine
+	}
+	id, err := db.Insert(r.model.table, v)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := r.model.primaryValue(rv); ok || r.model.primaryKey == nil {
+		return nil
+	}
+	return r.model.setPrimaryValue(rv, id)
+}
+
+// Save updates the struct by its primary key, or creates it when the key is empty
+func (r *Repository[T]) Save(v *T) error {
+	rv := reflect.ValueOf(v)
+	id, ok := r.model.primaryValue(rv)
+	if !ok {
+		return r.Create(v)
+	}
+
+	_, err := r.db.Update(r.model.table, v, func(b *builder.UpdateBuilder) error {
+		b.Where(b.Cond.EQ(r.model.primaryKey.column, id))
+		return nil
+	})
+	return err
+}
+
+// Delete removes the row with the given primary key
+func (r *Repository[T]) Delete(id interface{}) (int64, error) {
+	if r.model.primaryKey == nil {
+		return 0, errModelNoPK
+	}
+	return r.db.Delete(r.model.table, func(b *builder.DeleteBuilder) error {
+		b.Where(b.Cond.EQ(r.model.primaryKey.column, id))
+		return nil
+	})
+}
+
+// List returns the rows matched by fn
+func (r *Repository[T]) List(fn func(b *builder.SelectBuilder) error) ([]T, error) {
+	data, err := r.db.Find(r.model.table, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	var m []T
+	v := zreflect.ValueOf(&m)
+	if err = convMaps(data, v, r.db.codecs); err != nil {
+		return m, err
+	}
+	return m, r.db.preloadValues(v, newFindOption(r.opts))
+}
+
+// Page returns one page of rows matched by fn
+func (r *Repository[T]) Page(page, pagesize int, fn ...func(b *builder.SelectBuilder) error) ([]T, Pages, error) {
+	data, pages, err := r.db.Pages(r.model.table, page, pagesize, fn...)
+	if err != nil {
+		return nil, pages, err
+	}
+
+	var m []T
+	v := zreflect.ValueOf(&m)
+	if err = convMaps(data, v, r.db.codecs); err != nil {
+		return m, pages, err
+	}
+	return m, pages, r.db.preloadValues(v, newFindOption(r.opts))
+}
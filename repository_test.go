@@ -0,0 +1,188 @@
+//go:build go1.18
+// +build go1.18
+
+package zdb_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/zlsgo/zdb"
+	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver/sqlite3"
+	"github.com/zlsgo/zdb/testdata"
+)
+
+func TestRepository(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("repository")
+	tt.NoError(err)
+	defer clear()
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	err = testdata.InitTable(db)
+	tt.NoError(err)
+
+	repo, err := zdb.NewRepository[testdata.TestTableUser](db)
+	tt.NoError(err)
+	tt.Equal(testdata.TestTable.TableName(), repo.Table())
+
+	user := &testdata.TestTableUser{Name: "repo", Is: true}
+	err = repo.Create(user)
+	tt.NoError(err)
+	tt.EqualTrue(user.ID > 0)
+
+	found, err := repo.Get(user.ID)
+	tt.NoError(err)
+	tt.Equal("repo", found.Name)
+	tt.EqualTrue(found.Is)
+
+	found.Name = "repo2"
+	err = repo.Save(&found)
+	tt.NoError(err)
+
+	found, err = repo.Get(user.ID)
+	tt.NoError(err)
+	tt.Equal("repo2", found.Name)
+
+	err = repo.Save(&testdata.TestTableUser{Name: "repo3"})
+	tt.NoError(err)
+
+	list, err := repo.List(func(b *builder.SelectBuilder) error {
+		b.OrderBy("id").Asc()
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(2, len(list))
+	tt.Equal("repo3", list[1].Name)
+
+	items, pages, err := repo.Page(1, 1)
+	tt.NoError(err)
+	tt.Equal(1, len(items))
+	tt.Equal(uint(2), pages.Total)
+
+	affected, err := repo.Delete(user.ID)
+	tt.NoError(err)
+	tt.Equal(int64(1), affected)
+
+	_, err = repo.Get(user.ID)
+	tt.Equal(zdb.ErrNotFound, err)
+
+	_, err = zdb.NewRepository[fmt.Stringer](db)
+	tt.EqualTrue(err != nil)
+}
+
+type repositoryPKUser struct {
+	UID  int64  `zdb:"uid,pk"`
+	Name string `zdb:"name"`
+}
+
+func (repositoryPKUser) TableName() string {
+	return "repository_pk_user"
+}
+
+func TestRepositoryPrimaryKey(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("repository_pk")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	_, _ = db.Exec(`DROP TABLE repository_pk_user`)
+	_, err = db.Exec(`CREATE TABLE repository_pk_user (uid INTEGER PRIMARY KEY, name TEXT)`)
+	tt.NoError(err)
+
+	repo, err := zdb.NewRepository[repositoryPKUser](db)
+	tt.NoError(err)
+
+	user := &repositoryPKUser{Name: "pk"}
+	tt.NoError(repo.Create(user))
+	tt.EqualTrue(user.UID > 0)
+
+	found, err := repo.Get(user.UID)
+	tt.NoError(err)
+	tt.Equal("pk", found.Name)
+}
+
+func TestStructWriteZeroValue(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("struct_write_zero")
+	tt.NoError(err)
+	defer clear()
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	err = testdata.InitTable(db)
+	tt.NoError(err)
+
+	type user struct {
+		ID   int    `zdb:"id"`
+		Name string `zdb:"name,omitempty"`
+		Is   bool   `zdb:"is_ok"`
+	}
+
+	table := testdata.TestTable.TableName()
+	id, err := db.Insert(table, &user{Name: "zero", Is: true})
+	tt.NoError(err)
+
+	_, err = db.Update(table, &user{Is: false}, func(b *builder.UpdateBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+
+	row, err := db.FindOne(table, func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal("zero", row.Get("name").String())
+	tt.Equal(false, row.Get("is_ok").Bool())
+
+	_, err = db.Omit("name").Update(table, map[string]interface{}{"name": "omit", "is_ok": 1}, func(b *builder.UpdateBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+
+	_, err = db.Select("name").Update(table, &user{}, func(b *builder.UpdateBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+
+	row, err = db.FindOne(table, func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal("", row.Get("name").String())
+	tt.Equal(true, row.Get("is_ok").Bool())
+
+	_, err = db.Select("name").Select("is_ok").Update(table, &user{Name: "both"}, func(b *builder.UpdateBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+
+	row, err = db.FindOne(table, func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal("both", row.Get("name").String())
+	tt.Equal(false, row.Get("is_ok").Bool())
+}
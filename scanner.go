@@ -35,7 +35,7 @@ var (
 
 var convOption = func(conver *ztype.Conver) {
 	conver.ConvHook = func(name string, i reflect.Value, o reflect.Type) (reflect.Value, bool) {
-		if i.Type().AssignableTo(timeType) {
+		if i.Type().AssignableTo(timeType) && i.Type().ConvertibleTo(o) {
 			return i.Convert(o), false
 		}
 		return i, true
@@ -43,7 +43,7 @@ var convOption = func(conver *ztype.Conver) {
 }
 
 func Scan(rows IfeRows, out interface{}) (int, error) {
-	data, count, err := resolveDataFromRows(rows)
+	data, count, err := resolveDataFromRows(rows, DefaultScanOptions, 0)
 	if err != nil {
 		return 0, err
 	}
@@ -56,18 +56,74 @@ func Scan(rows IfeRows, out interface{}) (int, error) {
 		return 0, nil
 	}
 	if reflect.Indirect(v).Kind() != reflect.Slice {
-		return count, ztype.ValueConv(data[0], v, convOption)
+		return count, convMap(data[0], v, globalCodecs)
 	}
 
-	return count, ztype.ValueConv(data, v, convOption)
+	return count, convMaps(data, v, globalCodecs)
+}
+
+// convMap converts the row into out, structs are mapped through their zdb tags
+func convMap(data ztype.Map, out reflect.Value, codecs *codecRegistry) error {
+	typ := out.Type()
+	if typ.Kind() == reflect.Ptr && isNestedStruct(typ.Elem()) {
+		m, err := parseModel(typ.Elem(), "")
+		if err != nil {
+			return err
+		}
+		return m.decode(data, out, codecs)
+	}
+
+	return ztype.ValueConv(data, out, codecs.convOption)
+}
+
+// convMaps converts the rows into out, which must be a pointer to a slice
+func convMaps(data ztype.Maps, out reflect.Value, codecs *codecRegistry) error {
+	typ := out.Type()
+	if typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Slice {
+		return ztype.ValueConv(data, out, codecs.convOption)
+	}
+
+	sliceTyp := typ.Elem()
+	elemTyp := sliceTyp.Elem()
+	isPtr := elemTyp.Kind() == reflect.Ptr
+	if isPtr {
+		elemTyp = elemTyp.Elem()
+	}
+	if !isNestedStruct(elemTyp) {
+		return ztype.ValueConv(data, out, codecs.convOption)
+	}
+
+	m, err := parseModel(elemTyp, "")
+	if err != nil {
+		return err
+	}
+	list := reflect.MakeSlice(sliceTyp, len(data), len(data))
+	for i := range data {
+		item := list.Index(i)
+		if isPtr {
+			item.Set(reflect.New(elemTyp))
+			item = item.Elem()
+		}
+		if err = m.decode(data[i], item, codecs); err != nil {
+			return err
+		}
+	}
+	out.Elem().Set(list)
+	return nil
 }
 
 // ScanToMap returns the result in the form of []map[string]interface{}
 func ScanToMap(rows IfeRows) (ztype.Maps, int, error) {
-	return resolveDataFromRows(rows)
+	return resolveDataFromRows(rows, DefaultScanOptions, 0)
+}
+
+func (e *DB) scanToMap(rows IfeRows) (ztype.Maps, int, error) {
+	return resolveDataFromRows(rows, e.scanOptions, e.maxRows())
 }
 
-func resolveDataFromRows(rows IfeRows) (ztype.Maps, int, error) {
+// resolveDataFromRows scans rows into maps, with maxRows above 0 the rows
+// beyond it are dropped and ErrTooManyRows is returned
+func resolveDataFromRows(rows IfeRows, opt ScanOptions, maxRows int) (ztype.Maps, int, error) {
 	result := make([]ztype.Map, 0)
 	if nil == rows {
 		return result, 0, ErrNotFound
@@ -77,10 +133,14 @@ func resolveDataFromRows(rows IfeRows) (ztype.Maps, int, error) {
 		return result, 0, err
 	}
 	length := len(columns)
+	kinds := resolveColumnKinds(rows, length, opt)
 	values := make([]interface{}, length)
 	valuePtrs := make([]interface{}, length)
 	count := 0
 	for rows.Next() {
+		if maxRows > 0 && count >= maxRows {
+			return result, count, ErrTooManyRows
+		}
 		for i := 0; i < length; i++ {
 			valuePtrs[i] = &values[i]
 		}
@@ -91,6 +151,10 @@ func resolveDataFromRows(rows IfeRows) (ztype.Maps, int, error) {
 		entry := make(ztype.Map, length)
 		for i, col := range columns {
 			val := values[i]
+			if kinds != nil {
+				entry[col] = decodeColumn(kinds[i], val)
+				continue
+			}
 			switch v := val.(type) {
 			case []byte:
 				entry[col] = zstring.Bytes2String(v)
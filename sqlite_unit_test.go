@@ -1,11 +1,17 @@
 package zdb_test
 
 import (
+	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zjson"
 	"github.com/zlsgo/zdb"
 	"github.com/zlsgo/zdb/builder"
+	"github.com/zlsgo/zdb/driver"
+	"github.com/zlsgo/zdb/driver/mssql"
+	"github.com/zlsgo/zdb/driver/mysql"
 	"github.com/zlsgo/zdb/driver/sqlite3"
 	"github.com/zlsgo/zdb/testdata"
 )
@@ -204,6 +210,98 @@ func TestJsonTime(t *testing.T) {
 	json, err := jt.MarshalJSON()
 	tt.NoError(err)
 	tt.EqualTrue(len(json) > 0)
+
+	err = jt.UnmarshalJSON([]byte(`"2024-05-06 07:08:09"`))
+	tt.NoError(err)
+	tt.Equal("2024-05-06 07:08:09", jt.String())
+	err = jt.UnmarshalJSON([]byte(`null`))
+	tt.NoError(err)
+	tt.EqualTrue(jt.Time().IsZero())
+	tt.EqualTrue(jt.UnmarshalJSON([]byte(`"x"`)) != nil)
+
+	tt.NoError(jt.Scan([]byte("2024-05-06T07:08:09Z")))
+	tt.Equal(2024, jt.Time().Year())
+	tt.NoError(jt.Scan(nil))
+	tt.EqualTrue(jt.Time().IsZero())
+
+	old := zdb.DefaultJsonTimeOptions
+	defer func() { zdb.DefaultJsonTimeOptions = old }()
+	zdb.DefaultJsonTimeOptions.Layout = time.RFC3339
+	zdb.DefaultJsonTimeOptions.Location = time.UTC
+	zdb.DefaultJsonTimeOptions.ZeroAsNull = true
+
+	json, err = jt.MarshalJSON()
+	tt.NoError(err)
+	tt.Equal("null", string(json))
+	v, err := jt.Value()
+	tt.NoError(err)
+	tt.EqualTrue(v == nil)
+
+	jt = zdb.JsonTime(time.Date(2024, 5, 6, 7, 8, 9, 0, time.FixedZone("", 3600)))
+	tt.Equal("2024-05-06T06:08:09Z", jt.String())
+}
+
+func TestSQLiteJsonTime(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_json_time")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	_, _ = db.Exec(`DROP TABLE json_time`)
+	_, err = db.Exec(`CREATE TABLE json_time (id INTEGER PRIMARY KEY, created TEXT, updated DATETIME, deleted DATETIME)`)
+	tt.NoError(err)
+
+	type row struct {
+		ID      int           `zdb:"id"`
+		Created zdb.JsonTime  `zdb:"created"`
+		Updated zdb.JsonTime  `zdb:"updated"`
+		Deleted *zdb.JsonTime `zdb:"deleted"`
+	}
+
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.Local)
+	id, err := db.Insert("json_time", &row{Created: zdb.JsonTime(now), Updated: zdb.JsonTime(now)})
+	tt.NoError(err)
+
+	got, err := zdb.FindOne[row](db, "json_time", func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+	tt.EqualTrue(now.Equal(got.Created.Time()))
+	tt.EqualTrue(now.Equal(got.Updated.Time()))
+	tt.EqualTrue(got.Deleted == nil)
+
+	db.JsonTimeOptions(func(o *zdb.JsonTimeOptions) {
+		o.Location = time.UTC
+		o.ZeroAsNull = true
+	})
+	deleted := zdb.JsonTime(now)
+	id, err = db.Insert("json_time", &row{Updated: zdb.JsonTime(now), Deleted: &deleted})
+	tt.NoError(err)
+
+	m, err := db.FindOne("json_time", func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(nil, m["created"])
+
+	got, err = zdb.FindOne[row](db, "json_time", func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+	tt.EqualTrue(got.Created.Time().IsZero())
+	tt.EqualTrue(now.Equal(got.Deleted.Time()))
+	tt.Equal(time.UTC, got.Updated.Time().Location())
 }
 
 func TestMustInstance(t *testing.T) {
@@ -446,3 +544,757 @@ func TestDBBatchReplace(t *testing.T) {
 	tt.NoError(err)
 	tt.Equal(2, len(ids))
 }
+
+func TestSQLiteScanColumnTypes(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_scan_types")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	_, _ = db.Exec(`DROP TABLE scan_types`)
+	_, err = db.Exec(`CREATE TABLE scan_types (id INTEGER PRIMARY KEY, ok BOOLEAN, meta JSON, price DECIMAL(10,2))`)
+	tt.NoError(err)
+	_, err = db.Exec(`INSERT INTO scan_types (ok, meta, price) VALUES (1, '{"a":[1,2]}', 12.5)`)
+	tt.NoError(err)
+
+	rows, err := db.QueryToMaps(`SELECT * FROM scan_types`)
+	tt.NoError(err)
+	tt.Equal(int64(1), rows[0]["id"])
+	tt.Equal(true, rows[0]["ok"])
+	tt.Equal(map[string]interface{}{"a": []interface{}{1.0, 2.0}}, rows[0]["meta"])
+	tt.Equal(12.5, rows[0]["price"])
+
+	raw := db.ScanOptions(func(o *zdb.ScanOptions) {
+		o.Raw = true
+	})
+	rows, err = raw.QueryToMaps(`SELECT * FROM scan_types`)
+	tt.NoError(err)
+	tt.Equal(`{"a":[1,2]}`, rows[0]["meta"])
+
+	rows, err = db.QueryToMaps(`SELECT * FROM scan_types`)
+	tt.NoError(err)
+	tt.Equal(map[string]interface{}{"a": []interface{}{1.0, 2.0}}, rows[0]["meta"])
+}
+
+func TestSQLiteJSONColumn(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_json_column")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	_, _ = db.Exec(`DROP TABLE json_column`)
+	_, err = db.Exec(`CREATE TABLE json_column (id INTEGER PRIMARY KEY, meta TEXT, tags JSON, extra TEXT, res TEXT)`)
+	tt.NoError(err)
+
+	type meta struct {
+		Avatar string `json:"avatar"`
+		Level  int    `json:"level"`
+	}
+	type row struct {
+		ID    int                    `zdb:"id"`
+		Meta  meta                   `zdb:"meta,json"`
+		Tags  []string               `zdb:"tags,json"`
+		Extra map[string]interface{} `zdb:"extra,json"`
+		Res   *zjson.Res             `zdb:"res"`
+	}
+
+	id, err := db.Insert("json_column", &row{
+		Meta: meta{Avatar: "a.png", Level: 2},
+		Tags: []string{"x", "y"},
+		Res:  zjson.Parse(`{"k":"v"}`),
+	})
+	tt.NoError(err)
+
+	m, err := db.FindOne("json_column", func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(`{"avatar":"a.png","level":2}`, m.Get("meta").String())
+	tt.Equal(nil, m["extra"])
+
+	got, err := zdb.FindOne[row](db, "json_column", func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(meta{Avatar: "a.png", Level: 2}, got.Meta)
+	tt.Equal([]string{"x", "y"}, got.Tags)
+	tt.EqualTrue(got.Extra == nil)
+	tt.Equal("v", got.Res.Get("k").String())
+
+	_, err = db.Update("json_column", &row{Extra: map[string]interface{}{"n": 1}}, func(b *builder.UpdateBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+
+	got, err = zdb.FindOne[row](db, "json_column", func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.EQ("id", id))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(1.0, got.Extra["n"])
+	tt.Equal(0, len(got.Tags))
+}
+
+func TestSQLiteWhereBy(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_where_by")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	_, _ = db.Exec(`DROP TABLE where_by`)
+	_, err = db.Exec(`CREATE TABLE where_by (id INTEGER PRIMARY KEY, name TEXT, age INTEGER, status INTEGER, deleted_at DATETIME)`)
+	tt.NoError(err)
+
+	_, err = db.BatchInsert("where_by", []map[string]interface{}{
+		{"name": "alice", "age": 20, "status": 1},
+		{"name": "bob", "age": 16, "status": 1},
+		{"name": "carol", "age": 30, "status": 2},
+		{"name": "dave", "age": 40, "status": 3},
+	})
+	tt.NoError(err)
+
+	adult := map[string]interface{}{"age >=": 18, "status": []int{1, 2}, "deleted_at": nil}
+	rows, err := db.Find("where_by", func(b *builder.SelectBuilder) error {
+		b.WhereBy(adult).OrderBy("id")
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(2, len(rows))
+	tt.Equal("alice", rows[0].Get("name").String())
+
+	type filter struct {
+		Name   string `zdb:"name,like"`
+		MinAge int    `zdb:"age,>"`
+	}
+	rows, pages, err := db.Pages("where_by", 1, 1, func(b *builder.SelectBuilder) error {
+		b.WhereBy(filter{MinAge: 18}).OrderBy("id")
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(1, len(rows))
+	tt.Equal(uint(3), pages.Total)
+
+	n, err := db.Update("where_by", map[string]interface{}{"status": 9}, func(b *builder.UpdateBuilder) error {
+		b.WhereBy(map[string]interface{}{"$or": []map[string]interface{}{{"name": "bob"}, {"age >": 35}}})
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(int64(2), n)
+
+	n, err = db.Delete("where_by", func(b *builder.DeleteBuilder) error {
+		b.WhereBy(map[string]interface{}{"status": 9})
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(int64(2), n)
+
+	_, err = db.Delete("where_by", func(b *builder.DeleteBuilder) error {
+		b.WhereBy(map[string]interface{}{})
+		return nil
+	})
+	tt.EqualTrue(err != nil)
+}
+
+func TestSQLiteInsertFrom(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_insert_from")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	_, _ = db.Exec(`DROP TABLE insert_from`)
+	_, _ = db.Exec(`DROP TABLE insert_from_archive`)
+	_, err = db.Exec(`CREATE TABLE insert_from (id INTEGER PRIMARY KEY, name TEXT, status INTEGER)`)
+	tt.NoError(err)
+	_, err = db.Exec(`CREATE TABLE insert_from_archive (id INTEGER PRIMARY KEY, name TEXT)`)
+	tt.NoError(err)
+
+	_, err = db.BatchInsert("insert_from", []map[string]interface{}{
+		{"id": 1, "name": "a", "status": 1},
+		{"id": 2, "name": "b", "status": 0},
+		{"id": 3, "name": "c", "status": 0},
+	})
+	tt.NoError(err)
+
+	n, err := db.InsertFrom("insert_from_archive", []string{"id", "name"}, func(b *builder.SelectBuilder) error {
+		b.From("insert_from").Select("id", "name").Where(b.Cond.EQ("status", 0))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(int64(2), n)
+
+	n, err = db.InsertFrom("insert_from_archive", []string{"id", "name"}, func(b *builder.SelectBuilder) error {
+		b.From("insert_from").Select("id", "name")
+		return nil
+	}, "ON CONFLICT DO NOTHING")
+	tt.NoError(err)
+	tt.Equal(int64(1), n)
+
+	rows, err := db.Find("insert_from_archive", nil)
+	tt.NoError(err)
+	tt.Equal(3, len(rows))
+
+	_, err = db.InsertFrom("insert_from_archive", []string{"id"}, func(b *builder.SelectBuilder) error {
+		return nil
+	})
+	tt.EqualTrue(err != nil)
+}
+
+func TestSQLiteJoinedWrite(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_joined_write")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	_, _ = db.Exec(`DROP TABLE joined_user`)
+	_, _ = db.Exec(`DROP TABLE joined_ban`)
+	_, err = db.Exec(`CREATE TABLE joined_user (id INTEGER PRIMARY KEY, status INTEGER)`)
+	tt.NoError(err)
+	_, err = db.Exec(`CREATE TABLE joined_ban (id INTEGER PRIMARY KEY, user_id INTEGER, level INTEGER)`)
+	tt.NoError(err)
+
+	_, err = db.BatchInsert("joined_user", []map[string]interface{}{{"id": 1, "status": 1}, {"id": 2, "status": 1}, {"id": 3, "status": 1}})
+	tt.NoError(err)
+	_, err = db.BatchInsert("joined_ban", []map[string]interface{}{{"user_id": 2, "level": 5}, {"user_id": 3, "level": 1}})
+	tt.NoError(err)
+
+	n, err := db.Update("joined_user", map[string]interface{}{}, func(b *builder.UpdateBuilder) error {
+		b.Set(b.Assign("status", builder.Column("b.level")))
+		b.Join("joined_ban AS b", "b.user_id = joined_user.id").Where(b.Cond.GT("b.level", 0))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(int64(2), n)
+
+	n, err = db.Delete("joined_user AS u", func(b *builder.DeleteBuilder) error {
+		b.Join("joined_ban AS b", "b.user_id = u.id").Where(b.Cond.GE("b.level", 5))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(int64(1), n)
+
+	rows, err := db.Find("joined_user", func(b *builder.SelectBuilder) error {
+		b.OrderBy("id")
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(2, len(rows))
+	tt.Equal(1, rows[0].Get("status").Int())
+	tt.Equal(1, rows[1].Get("status").Int())
+
+	n, err = db.Update("joined_user u", map[string]interface{}{}, func(b *builder.UpdateBuilder) error {
+		b.Set(b.Assign("u.status", builder.Column("b.level")), b.Incr("u.id"))
+		b.Join("joined_ban b", "b.user_id = u.id").Where(b.Cond.EQ("b.level", 1))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(int64(1), n)
+
+	n, err = db.Delete("joined_user u", func(b *builder.DeleteBuilder) error {
+		b.Join("joined_ban b", "b.user_id + 1 = u.id").Where(b.Cond.EQ("b.level", 1))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(int64(1), n)
+
+	rows, err = db.Find("joined_user", func(b *builder.SelectBuilder) error {
+		b.OrderBy("id")
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(1, len(rows))
+	tt.Equal(1, rows[0].Get("id").Int())
+}
+
+func TestSQLiteJSONPath(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_json_path")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	_, _ = db.Exec(`DROP TABLE json_path`)
+	_, err = db.Exec(`CREATE TABLE json_path (id INTEGER PRIMARY KEY, profile TEXT)`)
+	tt.NoError(err)
+
+	_, err = db.BatchInsert("json_path", []map[string]interface{}{
+		{"id": 1, "profile": `{"age": 18, "city": {"name": "sz"}, "tags": ["go", "db"], "vip": true}`},
+		{"id": 2, "profile": `{"age": 20, "city": {"name": "gz"}, "tags": ["js"]}`},
+	})
+	tt.NoError(err)
+
+	rows, err := db.Find("json_path", func(b *builder.SelectBuilder) error {
+		b.Select("id", b.JSONField("profile", "city.name", "city"))
+		b.Where(b.Cond.JSONEQ("profile", "age", 18), b.Cond.JSONContains("profile", "tags", "go"), b.Cond.JSONHasKey("profile", "vip"))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(1, len(rows))
+	tt.Equal("sz", rows[0].Get("city").String())
+
+	rows, err = db.Find("json_path", func(b *builder.SelectBuilder) error {
+		b.Where(b.Cond.JSONEQ("profile", "vip", nil))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(1, len(rows))
+	tt.Equal(2, rows[0].Get("id").Int())
+}
+
+func TestSQLiteRowLock(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_row_lock")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	_, _ = db.Exec(`DROP TABLE row_lock`)
+	_, err = db.Exec(`CREATE TABLE row_lock (id INTEGER PRIMARY KEY, status INTEGER)`)
+	tt.NoError(err)
+	_, err = db.Insert("row_lock", map[string]interface{}{"id": 1, "status": 0})
+	tt.NoError(err)
+
+	err = db.Transaction(func(db *zdb.DB) error {
+		rows, err := db.Find("row_lock", func(b *builder.SelectBuilder) error {
+			b.Where(b.Cond.EQ("status", 0)).Limit(1).ForUpdate().SkipLocked().LockTimeout(time.Second)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		tt.Equal(1, len(rows))
+		return nil
+	})
+	tt.NoError(err)
+
+	_, err = db.Find("row_lock", func(b *builder.SelectBuilder) error {
+		b.SetDriver(&mysql.Config{}).ForUpdate().LockTimeout(time.Second)
+		return nil
+	})
+	tt.EqualTrue(err != nil)
+	tt.Equal("select error: LockTimeout requires a transaction", err.Error())
+}
+
+func TestSQLitePagesClone(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_pages_clone")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	_, _ = db.Exec(`DROP TABLE pages_clone`)
+	_, err = db.Exec(`CREATE TABLE pages_clone (id INTEGER PRIMARY KEY, status INTEGER)`)
+	tt.NoError(err)
+	for i := 1; i <= 5; i++ {
+		_, err = db.Insert("pages_clone", map[string]interface{}{"id": i, "status": i % 2})
+		tt.NoError(err)
+	}
+
+	builder.RegisterScope("pages_clone_odd", func(b *builder.SelectBuilder) {
+		b.Where(b.Cond.EQ("status", 1))
+	})
+
+	var query *builder.SelectBuilder
+	rows, pages, err := db.Pages("pages_clone", 1, 2, func(b *builder.SelectBuilder) error {
+		b.Select("id").UseScope("pages_clone_odd").OrderBy("id")
+		query = b
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(2, len(rows))
+	tt.Equal(uint(3), pages.Total)
+	tt.Equal(uint(2), pages.Count)
+	tt.Equal(`SELECT "id" FROM "pages_clone" WHERE "status" = 1 ORDER BY id LIMIT 2`, query.String())
+}
+
+func TestSQLiteExplain(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_explain")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	_, _ = db.Exec(`DROP TABLE explain_user`)
+	_, err = db.Exec(`CREATE TABLE explain_user (id INTEGER PRIMARY KEY, name TEXT, age INTEGER)`)
+	tt.NoError(err)
+	_, err = db.Exec(`CREATE INDEX idx_explain_user_name ON explain_user (name)`)
+	tt.NoError(err)
+
+	sb := builder.Query("explain_user").SetDriver(db.GetDriver())
+	sb.Where(sb.Cond.GT("age", 18))
+	plan, err := db.Explain(sb)
+	tt.NoError(err)
+	tt.Equal("SCAN", plan.Type)
+	tt.Equal("explain_user", plan.Table)
+	tt.EqualTrue(plan.FullScan)
+	tt.Equal(1, len(plan.FullScans()))
+
+	sb = builder.Query("explain_user").SetDriver(db.GetDriver())
+	sb.Where(sb.Cond.EQ("name", "a"))
+	plan, err = db.Explain(sb)
+	tt.NoError(err)
+	tt.Equal("SEARCH", plan.Type)
+	tt.Equal("idx_explain_user_name", plan.Index)
+	tt.Equal(0, len(plan.FullScans()))
+
+	_, err = db.Explain(sb, zdb.ExplainOptions{Analyze: true})
+	tt.EqualTrue(err != nil)
+
+	ub := builder.Update("explain_user").SetDriver(db.GetDriver())
+	ub.Set(ub.Assign("name", "b")).Where(ub.Cond.EQ("name", "a"))
+	_, err = db.Explain(ub, zdb.ExplainOptions{Analyze: true})
+	tt.EqualTrue(err != nil)
+	tt.Equal("explain error: Analyze would run *builder.UpdateBuilder, only SELECT is accepted", err.Error())
+
+	sb = builder.Query("explain_user")
+	sb.Where(sb.Cond.EQ("name", "a"))
+	plan, err = db.Explain(sb)
+	tt.NoError(err)
+	tt.Equal("idx_explain_user_name", plan.Index)
+
+	sb = builder.Query("explain_user").SetDriver(&mssql.Config{})
+	sb.Where(sb.Cond.EQ("name", "a")).Limit(1)
+	plan, err = db.Explain(sb)
+	tt.NoError(err)
+	tt.Equal("idx_explain_user_name", plan.Index)
+}
+
+func TestSQLiteStrict(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_strict")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	base, err := zdb.New(dbConf)
+	tt.NoError(err)
+	db := base.SetStrict(true).AllowColumns("strict_user", "id", "name")
+
+	_, _ = db.Exec(`DROP TABLE strict_user`)
+	_, err = db.Exec(`CREATE TABLE strict_user (id INTEGER PRIMARY KEY, name TEXT, is_admin INTEGER DEFAULT 0)`)
+	tt.NoError(err)
+
+	_, err = db.Insert("strict_user", map[string]interface{}{"id": 1, "name": "a"})
+	tt.NoError(err)
+	_, err = db.Insert("strict_user", map[string]interface{}{"id": 2, "name": "b", "is_admin": 1})
+	tt.EqualTrue(err != nil)
+
+	_, err = db.Update("strict_user", map[string]interface{}{"is_admin": 1}, func(b *builder.UpdateBuilder) error {
+		b.Where(b.Cond.EQ("id", 1))
+		return nil
+	})
+	tt.EqualTrue(err != nil)
+	_, err = db.Update("strict_user", map[string]interface{}{"name": "c"}, func(b *builder.UpdateBuilder) error {
+		b.Where(b.Cond.EQ("id", 1))
+		return nil
+	})
+	tt.NoError(err)
+
+	_, err = db.Find("strict_user", func(b *builder.SelectBuilder) error {
+		b.Select("count(*)")
+		return nil
+	})
+	tt.EqualTrue(err != nil)
+
+	rows, pages, err := db.Pages("strict_user", 1, 10, func(b *builder.SelectBuilder) error {
+		b.Select("id", "name").OrderBy("id DESC")
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(1, len(rows))
+	tt.Equal("c", rows[0].Get("name").String())
+	tt.Equal(uint(1), pages.Total)
+
+	_, err = base.Find("strict_user", func(b *builder.SelectBuilder) error {
+		b.Select("count(*)")
+		return nil
+	})
+	tt.NoError(err)
+	_, err = base.Update("strict_user", map[string]interface{}{"is_admin": 1}, func(b *builder.UpdateBuilder) error {
+		b.Where(b.Cond.EQ("id", 1))
+		return nil
+	})
+	tt.NoError(err)
+}
+
+func TestSQLiteGuard(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_guard")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	raw, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	var audits []string
+	db := raw.Guard(func(o *zdb.GuardOptions) {
+		o.RequireWhere = true
+		o.BlockDDL = true
+		o.LargeTables = []string{"guard_log"}
+		o.MaxRows = 3
+		o.Audit = func(reason, query string, args []interface{}) {
+			audits = append(audits, reason+": "+query)
+		}
+	})
+
+	_, err = db.Exec(`CREATE TABLE guard_log (id INTEGER PRIMARY KEY, status INTEGER)`)
+	tt.EqualTrue(err != nil)
+	err = db.Migration(func(db *zdb.DB, d driver.Dialect) error {
+		_, _ = db.Exec(`DROP TABLE IF EXISTS guard_log`)
+		_, err := db.Exec(`CREATE TABLE guard_log (id INTEGER PRIMARY KEY, status INTEGER)`)
+		return err
+	})
+	tt.NoError(err)
+
+	for i := 1; i <= 5; i++ {
+		_, err = db.Insert("guard_log", map[string]interface{}{"id": i, "status": 0})
+		tt.NoError(err)
+	}
+
+	_, err = db.Exec(`UPDATE guard_log SET status = 1`)
+	tt.EqualTrue(err != nil)
+	_, err = db.Exec(`DELETE FROM guard_log`)
+	tt.EqualTrue(err != nil)
+	_, err = db.Exec(`UPDATE guard_log SET status = 1 WHERE id = ?`, 1)
+	tt.NoError(err)
+
+	_, err = db.Find("guard_log", nil)
+	tt.EqualTrue(err != nil)
+	rows, err := db.Find("guard_log", func(b *builder.SelectBuilder) error {
+		b.Limit(2)
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(2, len(rows))
+
+	rows, err = db.QueryToMaps(`SELECT * FROM guard_log`)
+	tt.Equal(zdb.ErrTooManyRows, err)
+	tt.Equal(3, len(rows))
+
+	tt.EqualTrue(db.Unsafe("", func(db *zdb.DB) error { return nil }) != nil)
+	err = db.Unsafe("reset status", func(db *zdb.DB) error {
+		if _, err := db.Exec(`UPDATE guard_log SET status = 0`); err != nil {
+			return err
+		}
+		rows, err := db.QueryToMaps(`SELECT * FROM guard_log`)
+		tt.Equal(5, len(rows))
+		return err
+	})
+	tt.NoError(err)
+	tt.Equal([]string{"reset status: UPDATE guard_log SET status = 0", "reset status: SELECT * FROM guard_log"}, audits)
+
+	_, err = raw.Exec(`UPDATE guard_log SET status = 2`)
+	tt.NoError(err)
+	rows, err = raw.QueryToMaps(`SELECT * FROM guard_log`)
+	tt.NoError(err)
+	tt.Equal(5, len(rows))
+}
+
+func TestSQLiteSetOperations(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_set_operations")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	_, _ = db.Exec(`DROP TABLE set_a`)
+	_, _ = db.Exec(`DROP TABLE set_b`)
+	_, err = db.Exec(`CREATE TABLE set_a (id INTEGER)`)
+	tt.NoError(err)
+	_, err = db.Exec(`CREATE TABLE set_b (id INTEGER)`)
+	tt.NoError(err)
+	_, err = db.Exec(`INSERT INTO set_a (id) VALUES (1), (2), (3), (4)`)
+	tt.NoError(err)
+	_, err = db.Exec(`INSERT INTO set_b (id) VALUES (3), (4), (5)`)
+	tt.NoError(err)
+
+	query := func(table string) *builder.SelectBuilder {
+		return builder.Query(table).Select("id").SetDriver(db.GetDriver())
+	}
+
+	b := builder.Except(query("set_a"), query("set_b")).SetDriver(db.GetDriver())
+	b.Union(builder.Intersect(query("set_a"), query("set_b")).SetDriver(db.GetDriver()))
+	b.OrderBy("id").Desc().Limit(3).Offset(1)
+	sql, values, err := b.Build()
+	tt.NoError(err)
+
+	rows, err := db.QueryToMaps(sql, values...)
+	tt.NoError(err)
+	ids := make([]int, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.Get("id").Int())
+	}
+	tt.Equal([]int{3, 2, 1}, ids)
+}
+
+func TestSQLiteExpr(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_expr")
+	tt.NoError(err)
+	defer clear()
+
+	if _, ok := dbConf.(*sqlite3.Config); !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(dbConf)
+	tt.NoError(err)
+
+	_, _ = db.Exec(`DROP TABLE expr_user`)
+	_, err = db.Exec(`CREATE TABLE expr_user (id INTEGER PRIMARY KEY, first TEXT, last TEXT, nickname TEXT, score INTEGER, grade TEXT, created_at TEXT)`)
+	tt.NoError(err)
+	_, err = db.Exec(`INSERT INTO expr_user (id, first, last, nickname, score, created_at) VALUES
+		(1, 'Ada', 'Lovelace', NULL, 95, '2024-03-15 10:20:30'), (2, 'Alan', 'Turing', 'at', 50, '2024-03-16 08:00:00')`)
+	tt.NoError(err)
+
+	_, err = db.Update("expr_user", map[string]interface{}{
+		"grade": builder.Case().When(builder.Raw("score >= 60"), "pass").Else("fail"),
+	}, func(b *builder.UpdateBuilder) error {
+		b.Where(b.Cond.GT("id", 0))
+		return nil
+	})
+	tt.NoError(err)
+
+	rows, err := db.Find("expr_user", func(b *builder.SelectBuilder) error {
+		b.Select(
+			"id", "grade",
+			b.As(b.Cond.Var(builder.Coalesce(builder.Column("nickname"), builder.Concat(builder.Column("first"), " ", builder.Column("last")))), "name"),
+			b.As(b.Cond.Var(builder.DateTrunc("month", builder.Column("created_at"))), "month"),
+			b.As(b.Cond.Var(builder.DateFormat(builder.Column("created_at"), "%H:%M")), "at"),
+		)
+		b.Where(b.Cond.Var(builder.Cast(builder.Column("score"), "string")) + " <> " + b.Cond.Var(""))
+		b.OrderBy(b.Cond.Var(builder.Case().When(b.Cond.LT("score", 60), 0).Else(1)))
+		return nil
+	})
+	tt.NoError(err)
+	tt.Equal(2, len(rows))
+	tt.Equal("at", rows[0].Get("name").String())
+	tt.Equal("fail", rows[0].Get("grade").String())
+	tt.Equal("Ada Lovelace", rows[1].Get("name").String())
+	tt.Equal("pass", rows[1].Get("grade").String())
+	tt.Equal("2024-03-01 00:00:00", rows[1].Get("month").String())
+	tt.Equal("10:20", rows[1].Get("at").String())
+}
+
+type versionedSQLite struct {
+	*sqlite3.Config
+	version string
+}
+
+func (c *versionedSQLite) ServerVersion() string {
+	return c.version
+}
+
+func (c *versionedSQLite) DetectVersion(db *sql.DB) (err error) {
+	c.version, err = driver.ReadVersion(db, "SELECT sqlite_version()")
+	return
+}
+
+func TestSQLiteDetectVersion(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	dbConf, clear, err := testdata.GetDbConf("sqlite_version")
+	tt.NoError(err)
+	defer clear()
+
+	conf, ok := dbConf.(*sqlite3.Config)
+	if !ok {
+		t.Skip("sqlite only")
+	}
+
+	db, err := zdb.New(&versionedSQLite{Config: conf})
+	tt.NoError(err)
+
+	v, ok := db.GetDriver().(driver.Versioner)
+	tt.EqualTrue(ok)
+	tt.EqualTrue(v.ServerVersion() != "")
+	tt.EqualTrue(!driver.VersionBelow(db.GetDriver(), "3.0"))
+	tt.EqualTrue(driver.VersionBelow(db.GetDriver(), "999.0"))
+}
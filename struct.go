@@ -5,11 +5,10 @@ package zdb
 
 import (
 	"github.com/sohaha/zlsgo/zreflect"
-	"github.com/sohaha/zlsgo/ztype"
 	"github.com/zlsgo/zdb/builder"
 )
 
-func Find[T any](e *DB, table string, fn func(b *builder.SelectBuilder) error) ([]T, error) {
+func Find[T any](e *DB, table string, fn func(b *builder.SelectBuilder) error, opts ...FindOption) ([]T, error) {
 	data, err := e.Find(table, fn)
 	if err != nil {
 		return nil, err
@@ -17,10 +16,13 @@ func Find[T any](e *DB, table string, fn func(b *builder.SelectBuilder) error) (
 	var m []T
 
 	v := zreflect.ValueOf(&m)
-	return m, ztype.ValueConv(data, v, convOption)
+	if err = convMaps(data, v, e.codecs); err != nil {
+		return m, err
+	}
+	return m, e.preloadValues(v, newFindOption(opts))
 }
 
-func FindOne[T any](e *DB, table string, fn func(b *builder.SelectBuilder) error) (T, error) {
+func FindOne[T any](e *DB, table string, fn func(b *builder.SelectBuilder) error, opts ...FindOption) (T, error) {
 	var m T
 	data, err := e.FindOne(table, fn)
 	if err != nil {
@@ -28,5 +30,8 @@ func FindOne[T any](e *DB, table string, fn func(b *builder.SelectBuilder) error
 	}
 
 	v := zreflect.ValueOf(&m)
-	return m, ztype.ValueConv(data, v, convOption)
+	if err = convMap(data, v, e.codecs); err != nil {
+		return m, err
+	}
+	return m, e.preloadValues(v, newFindOption(opts))
 }
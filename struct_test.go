@@ -4,9 +4,139 @@
 package zdb
 
 import (
+	"reflect"
 	"testing"
+
+	"github.com/sohaha/zlsgo"
+	"github.com/sohaha/zlsgo/zjson"
+	"github.com/sohaha/zlsgo/ztype"
 )
 
 func TestNewStruct(t *testing.T) {
 
 }
+
+type writeTagsModel struct {
+	ID        int    `zdb:"id"`
+	Name      string `zdb:"name,omitempty"`
+	Count     int    `zdb:"count"`
+	IsOk      bool   `zdb:"is_ok"`
+	Ignore    string `zdb:"-"`
+	Total     int    `zdb:"total,readonly"`
+	CreatedBy string `zdb:"created_by,insertonly"`
+}
+
+func TestParseStructWrite(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	data := &writeTagsModel{Ignore: "x", Total: 9, CreatedBy: "root"}
+	cols, args, err := parseStruct(data, "id", writeInsert, nil, nil)
+	tt.NoError(err)
+	tt.Equal([]string{"count", "is_ok", "created_by"}, cols)
+	tt.Equal([]interface{}{0, false, "root"}, args[0])
+
+	data.ID = 1
+	cols, args, err = parseStruct(data, "id", writeUpdate, nil, nil)
+	tt.NoError(err)
+	tt.Equal([]string{"count", "is_ok"}, cols)
+	tt.Equal([]interface{}{0, false}, args[0])
+
+	cols, _, err = parseStruct(data, "id", writeInsert, []string{"id", "name", "total"}, nil)
+	tt.NoError(err)
+	tt.Equal([]string{"id", "name"}, cols)
+
+	cols, _, err = parseStruct(data, "id", writeUpdate, nil, []string{"count"})
+	tt.NoError(err)
+	tt.Equal([]string{"is_ok"}, cols)
+
+	_, _, err = parseStruct([]writeTagsModel{{Count: 1}, {Name: "b"}}, "id", writeInsert, nil, nil)
+	tt.EqualTrue(err != nil)
+
+	cols, args, err = parseStruct([]writeTagsModel{{Count: 1}, {Count: 2}}, "id", writeInsert, nil, nil)
+	tt.NoError(err)
+	tt.Equal([]string{"count", "is_ok", "created_by"}, cols)
+	tt.Equal([]interface{}{1, false, ""}, args[0])
+	tt.Equal([]interface{}{2, false, ""}, args[1])
+
+	cols, args, err = parseStruct([]writeTagsModel{{Name: "a"}, {Name: "b"}}, "id", writeInsert, nil, nil)
+	tt.NoError(err)
+	tt.Equal([]string{"name", "count", "is_ok", "created_by"}, cols)
+	tt.Equal([]interface{}{"a", 0, false, ""}, args[0])
+	tt.Equal([]interface{}{"b", 0, false, ""}, args[1])
+}
+
+type jsonTypeModel struct {
+	ID   int        `zdb:"id"`
+	Meta zjson.Res  `zdb:"meta"`
+	Res  *zjson.Res `zdb:"res"`
+	Name string     `zdb:"name"`
+}
+
+func TestParseStructJSONType(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	cols, args, err := parseStruct(&jsonTypeModel{
+		Meta: *zjson.Parse(`{"a":1}`),
+		Res:  zjson.Parse(`[1,2]`),
+		Name: "n",
+	}, "id", writeInsert, nil, nil)
+	tt.NoError(err)
+	tt.Equal([]string{"meta", "res", "name"}, cols)
+	tt.Equal([]interface{}{`{"a":1}`, `[1,2]`, "n"}, args[0])
+
+	var m jsonTypeModel
+	err = convMap(ztype.Map{"meta": `{"a":2}`, "res": `{"b":3}`, "name": "x"}, reflect.ValueOf(&m), nil)
+	tt.NoError(err)
+	tt.Equal(2, m.Meta.Get("a").Int())
+	tt.Equal(3, m.Res.Get("b").Int())
+}
+
+type nestedBaseModel struct {
+	ID   int      `zdb:"id"`
+	Date JsonTime `zdb:"date"`
+}
+
+type nestedProfile struct {
+	Avatar string `zdb:"avatar"`
+}
+
+type nestedUser struct {
+	nestedBaseModel
+	Name    string         `zdb:"name"`
+	Profile nestedProfile  `zdb:"profile"`
+	Extra   *nestedProfile `zdb:"extra"`
+}
+
+type nestedComposite struct {
+	User    nestedUser    `zdb:"u,prefix"`
+	Profile nestedProfile `zdb:"p"`
+}
+
+func TestParseStructNested(t *testing.T) {
+	tt := zlsgo.NewTest(t)
+
+	cols, args, err := parseStruct(&nestedUser{nestedBaseModel: nestedBaseModel{ID: 1}, Name: "a", Profile: nestedProfile{Avatar: "x"}}, "id", writeInsert, nil, nil)
+	tt.NoError(err)
+	tt.Equal([]string{"id", "date", "name"}, cols)
+	tt.Equal(3, len(args[0]))
+
+	var u nestedUser
+	m, err := parseModel(reflect.TypeOf(u), "")
+	tt.NoError(err)
+	err = m.decode(ztype.Map{"id": 2, "NAME": "b", "profile.avatar": "p1", "extra__avatar": "p2"}, reflect.ValueOf(&u), globalCodecs)
+	tt.NoError(err)
+	tt.Equal(2, u.ID)
+	tt.Equal("b", u.Name)
+	tt.Equal("p1", u.Profile.Avatar)
+	tt.Equal("p2", u.Extra.Avatar)
+
+	var list []*nestedComposite
+	err = convMaps(ztype.Maps{{"u.id": 3, "u.name": "c", "u.profile.avatar": "p3", "p__avatar": "p4"}}, reflect.ValueOf(&list), globalCodecs)
+	tt.NoError(err)
+	tt.Equal(1, len(list))
+	tt.Equal(3, list[0].User.ID)
+	tt.Equal("c", list[0].User.Name)
+	tt.Equal("p3", list[0].User.Profile.Avatar)
+	tt.Equal("p4", list[0].Profile.Avatar)
+	tt.EqualTrue(list[0].User.Extra == nil)
+}
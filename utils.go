@@ -1,7 +1,6 @@
 package zdb
 
 import (
-	"bytes"
 	"errors"
 	"reflect"
 	"strconv"
@@ -10,8 +9,6 @@ import (
 	"unsafe"
 
 	"github.com/sohaha/zlsgo/zlog"
-	"github.com/sohaha/zlsgo/zreflect"
-	"github.com/sohaha/zlsgo/zstring"
 	"github.com/sohaha/zlsgo/ztype"
 	"github.com/zlsgo/zdb/builder"
 )
@@ -36,25 +33,6 @@ func (u utils) SetLog(l *zlog.Logger) {
 	log = l
 }
 
-func (j JsonTime) String() string {
-	t := time.Time(j)
-	if t.IsZero() {
-		return "0000-00-00 00:00:00"
-	}
-	return t.Format("2006-01-02 15:04:05")
-}
-
-func (j JsonTime) Time() time.Time {
-	return time.Time(j)
-}
-
-func (j JsonTime) MarshalJSON() ([]byte, error) {
-	res := bytes.NewBufferString("\"")
-	res.WriteString(j.String())
-	res.WriteString("\"")
-	return res.Bytes(), nil
-}
-
 func parseQuery(e *DB, b builder.Builder) (ztype.Maps, error) {
 	sql, values, err := b.Build()
 	if err != nil {
@@ -65,13 +43,27 @@ func parseQuery(e *DB, b builder.Builder) (ztype.Maps, error) {
 		zlog.Debug(sql, values)
 	}
 
-	rows, err := e.Query(sql, values...)
+	if sb, ok := b.(*builder.SelectBuilder); ok {
+		if set, reset := sb.LockTimeoutStatements(); set != "" {
+			if e.session == nil {
+				return make(ztype.Maps, 0), errors.New("select error: LockTimeout requires a transaction")
+			}
+			if _, err = e.Exec(set); err != nil {
+				return make(ztype.Maps, 0), err
+			}
+			if reset != "" {
+				defer func() { _, _ = e.Exec(reset) }()
+			}
+		}
+	}
+
+	rows, err := e.query(sql, values)
 	if err != nil {
 		return make(ztype.Maps, 0), err
 	}
 	defer rows.Close()
 
-	result, total, err := ScanToMap(rows)
+	result, total, err := e.scanToMap(rows)
 	if total == 0 {
 		return make(ztype.Maps, 0), ErrNotFound
 	}
@@ -89,7 +81,7 @@ func parseExec(e *DB, b builder.Builder) (int64, error) {
 		zlog.Debug(sql, values)
 	}
 
-	result, err := e.Exec(sql, values...)
+	result, err := e.exec(sql, values)
 	if err != nil {
 		return 0, err
 	}
@@ -157,61 +149,111 @@ func parseValues(data interface{}) (cols []string, args [][]interface{}, err err
 	return cols, args, err
 }
 
-func parseStruct(data interface{}) (cols []string, args [][]interface{}, err error) {
-	vof := reflect.ValueOf(data)
-	vof = reflect.Indirect(vof)
+func parseStruct(data interface{}, idKey string, mode writeMode, only, omit []string) (cols []string, args [][]interface{}, err error) {
+	vof := reflect.Indirect(reflect.ValueOf(data))
 	kind := vof.Kind()
 	if kind == reflect.Struct {
-		typ := vof.Type()
-		numField := vof.NumField()
-		cols = make([]string, 0, numField)
-		colArgs := make([]interface{}, 0, numField)
-		for i := 0; i < numField; i++ {
-			field := vof.Field(i)
-			if field.IsZero() {
-				continue
-			}
-			v := field.Interface()
-			structField := typ.Field(i)
-			name := structField.Name
-			if zstring.IsLcfirst(name) {
-				continue
-			}
-			tag, _ := zreflect.GetStructTag(structField)
-			if tag != "" {
-				name = tag
-			}
-			cols = append(cols, name)
-			colArgs = append(colArgs, v)
+		m, err := parseModel(vof.Type(), idKey)
+		if err != nil {
+			return nil, nil, err
 		}
-
-		args = append(args, colArgs)
-		return
+		cols, colArgs, err := m.columns(vof, mode, only, omit)
+		if err != nil {
+			return nil, nil, err
+		}
+		return cols, [][]interface{}{colArgs}, nil
 	} else if kind == reflect.Slice {
-		for i := 0; i < vof.Len(); i++ {
-			val := vof.Index(i).Interface()
-			col, arg, err := parseStruct(val)
-			if err != nil {
-				return nil, nil, err
-			}
-			if i == 0 {
-				cols = col
-			}
-			args = append(args, arg[0])
+		if vof.Len() == 0 {
+			return nil, nil, errDataInvalid
 		}
-		return
+		m, err := parseModel(vof.Type().Elem(), idKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		return m.batchColumns(vof, mode, only, omit)
 	}
 
 	err = errors.New("insert data is illegal")
 	return
 }
 
-func parseAll(data interface{}) (cols []string, args [][]interface{}, err error) {
-	cols, args, err = parseValues(data)
-	if err != nil && err == errDataInvalid {
-		cols, args, err = parseStruct(data)
+func isStructData(data interface{}) bool {
+	typ := reflect.TypeOf(data)
+	if typ == nil {
+		return false
 	}
-	return
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+	}
+	return typ.Kind() == reflect.Struct && typ != timeType
+}
+
+// parseWrite resolves the columns and values written by Insert/Update,
+// structs honour their zdb tag options, maps are filtered by Select/Omit
+// and the columns are checked against AllowColumns of table
+func (e *DB) parseWrite(table string, data interface{}, mode writeMode) ([]string, [][]interface{}, error) {
+	var (
+		cols []string
+		args [][]interface{}
+		err  error
+	)
+	if isStructData(data) {
+		cols, args, err = parseStruct(data, e.idKey, mode, e.writeOnly, e.writeOmit)
+	} else {
+		cols, args, err = parseMap(filterMap(ztype.ToMap(data), e.writeOnly, e.writeOmit), nil)
+	}
+	if err != nil {
+		return cols, args, err
+	}
+	if err = e.checkColumns(table, cols); err != nil {
+		return cols, args, err
+	}
+	return cols, args, nil
+}
+
+// parseWrites is the batch variant of parseWrite
+func (e *DB) parseWrites(table string, data interface{}, mode writeMode) ([]string, [][]interface{}, error) {
+	var (
+		cols []string
+		args [][]interface{}
+		err  error
+	)
+	if isStructData(data) {
+		cols, args, err = parseStruct(data, e.idKey, mode, e.writeOnly, e.writeOmit)
+	} else {
+		val := ztype.ToMaps(data)
+		for i := range val {
+			val[i] = filterMap(val[i], e.writeOnly, e.writeOmit)
+		}
+		cols, args, err = parseMaps2(val)
+	}
+	if err != nil {
+		return cols, args, err
+	}
+	if err = e.checkColumns(table, cols); err != nil {
+		return cols, args, err
+	}
+	return cols, args, nil
+}
+
+func filterMap(val ztype.Map, only, omit []string) ztype.Map {
+	if len(only) == 0 && len(omit) == 0 {
+		return val
+	}
+	data := make(ztype.Map, len(val))
+	for k := range val {
+		if inColumns(omit, k) || (len(only) > 0 && !inColumns(only, k)) {
+			continue
+		}
+		data[k] = val[k]
+	}
+	return data
 }
 
 func parseMap(val ztype.Map, specify []string) ([]string, [][]interface{}, error) {